@@ -0,0 +1,123 @@
+package nbt
+
+// Clone returns a deep copy of tag, sharing nothing with it.
+func (tag *NamedTag) Clone() *NamedTag {
+	if tag == nil {
+		return nil
+	}
+	return &NamedTag{tag.Type, tag.Name, clonePayload(tag.Payload)}
+}
+
+func (tag *Tag) Clone() *Tag {
+	if tag == nil {
+		return nil
+	}
+	return &Tag{tag.Type, clonePayload(tag.Payload)}
+}
+
+func (l *List) Clone() *List {
+	if l == nil {
+		return nil
+	}
+
+	array := l.Array
+	switch a := l.Array.(type) {
+	case []int8:
+		array = append(a[:0:0], a...)
+	case []int16:
+		array = append(a[:0:0], a...)
+	case []int32:
+		array = append(a[:0:0], a...)
+	case []int64:
+		array = append(a[:0:0], a...)
+	case []float32:
+		array = append(a[:0:0], a...)
+	case []float64:
+		array = append(a[:0:0], a...)
+	case []string:
+		array = append(a[:0:0], a...)
+	case [][]byte:
+		b := make([][]byte, len(a))
+		for i := range a {
+			b[i] = append(a[i][:0:0], a[i]...)
+		}
+		array = b
+	case []*List:
+		b := make([]*List, len(a))
+		for i := range a {
+			b[i] = a[i].Clone()
+		}
+		array = b
+	case []Compound:
+		b := make([]Compound, len(a))
+		for i := range a {
+			b[i] = a[i].Clone()
+		}
+		array = b
+	case []*OrderedCompound:
+		b := make([]*OrderedCompound, len(a))
+		for i := range a {
+			b[i] = a[i].Clone()
+		}
+		array = b
+	case [][]int32:
+		b := make([][]int32, len(a))
+		for i := range a {
+			b[i] = append(a[i][:0:0], a[i]...)
+		}
+		array = b
+	case [][]int64:
+		b := make([][]int64, len(a))
+		for i := range a {
+			b[i] = append(a[i][:0:0], a[i]...)
+		}
+		array = b
+	}
+	return &List{l.Type, array}
+}
+
+func (m Compound) Clone() Compound {
+	if m == nil {
+		return nil
+	}
+
+	c := make(Compound, len(m))
+	for name, tag := range m {
+		c[name] = tag.Clone()
+	}
+	return c
+}
+
+func (c *OrderedCompound) Clone() *OrderedCompound {
+	if c == nil {
+		return nil
+	}
+
+	_c := &OrderedCompound{names: append(c.names[:0:0], c.names...), tags: make(map[string]*Tag, len(c.tags))}
+	for name, tag := range c.tags {
+		_c.tags[name] = tag.Clone()
+	}
+	return _c
+}
+
+// clonePayload deep copies payloads that are not immutable.
+func clonePayload(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case []byte:
+		return append(p[:0:0], p...)
+	case []int32:
+		return append(p[:0:0], p...)
+	case []int64:
+		return append(p[:0:0], p...)
+	case RawTag:
+		return append(p[:0:0], p...)
+	case *List:
+		return p.Clone()
+	case Compound:
+		return p.Clone()
+	case *OrderedCompound:
+		return p.Clone()
+	default:
+		return payload
+	}
+}
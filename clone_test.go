@@ -0,0 +1,63 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClone(t *testing.T) {
+	tag := testTag.Clone()
+	if diff := cmp.Diff(testTag, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	// modifying the clone must not modify the original
+	m := tag.Payload.(Compound)
+	for name, child := range m {
+		switch p := child.Payload.(type) {
+		case []byte:
+			if len(p) > 0 {
+				p[0]++
+			}
+		case []int32:
+			if len(p) > 0 {
+				p[0]++
+			}
+		case []int64:
+			if len(p) > 0 {
+				p[0]++
+			}
+		case *List:
+			p.Array = nil
+		case Compound:
+			p["x"] = &Tag{TypeByte, int8(1)}
+		}
+		child.Type = TypeEnd
+		m[name+"_"] = child
+	}
+
+	if diff := cmp.Diff(testTag, testTag.Clone()); diff != "" {
+		t.Fatalf("original modified: cmp.Diff(expected, got):\n%v", diff)
+	}
+	if testTag.Equal(tag) {
+		t.Fatal("expected modified clone to differ")
+	}
+}
+
+func TestCloneOrdered(t *testing.T) {
+	c := NewOrderedCompound()
+	c.Set("b", &Tag{TypeIntArray, []int32{1, 2}})
+	c.Set("a", &Tag{TypeList, &List{TypeCompound, []*OrderedCompound{NewOrderedCompound()}}})
+
+	clone := c.Clone()
+	clone.Get("b").Payload.([]int32)[0] = 3
+	clone.Set("c", &Tag{TypeByte, int8(0)})
+
+	if c.Get("b").Payload.([]int32)[0] != 1 || c.Len() != 2 {
+		t.Fatal("original modified")
+	}
+	if diff := cmp.Diff([]string{"b", "a", "c"}, clone.Names()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
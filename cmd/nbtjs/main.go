@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strconv"
 
@@ -93,13 +94,13 @@ func nbtToJSON(in *os.File, out *os.File) {
 		}
 		defer closeIO(r, in.Name())
 
-		dec = nbt.NewDecoder(r)
+		dec = nbt.NewDecoderOptions(r, nbt.DecoderOptions{OrderedCompounds: true})
 	} else {
 		_, err := in.Seek(0, 0)
 		if err != nil {
 			fatal(in.Name(), err)
 		}
-		dec = nbt.NewDecoder(in)
+		dec = nbt.NewDecoderOptions(in, nbt.DecoderOptions{OrderedCompounds: true})
 	}
 
 	tag, err := dec.Decode()
@@ -116,10 +117,13 @@ func nbtToJSON(in *os.File, out *os.File) {
 }
 
 func jsonToNBT(in *os.File, out *os.File) {
-	dec := json.NewDecoder(in)
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		fatal(in.Name(), err)
+	}
 
-	tag := new(nbt.NamedTag)
-	if err := dec.Decode(tag); err != nil {
+	tag, err := nbt.UnmarshalJSONOrdered(data)
+	if err != nil {
 		fatal(in.Name(), err)
 	}
 
@@ -1,39 +1,583 @@
 package nbt
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/pkg/errors"
 )
 
 type Decoder struct {
-	r *offsetReader
+	r       *offsetReader
+	tokens  *Reader
+	order   binary.ByteOrder
+	network bool
+
+	// filter is set during DecodeFilter, except below a tag it keeps
+	filter Filter
+
+	// bigEndian is set if order is binary.BigEndian, whose methods can be
+	// inlined when called directly
+	bigEndian bool
+	raw       bool
+
+	namelessRoot    bool
+	disallowEndRoot bool
+	ordered         bool
+
+	maxDepth       int
+	maxArrayLength int
+}
+
+// DefaultMaxDepth is the nesting limit used when DecoderOptions.MaxDepth is
+// zero, which is the same as Minecraft's.
+const DefaultMaxDepth = 512
+
+type DecoderOptions struct {
+	// ByteOrder of numeric payloads and length prefixes. Defaults to
+	// binary.BigEndian as used by Java Edition; Bedrock Edition files use
+	// binary.LittleEndian.
+	ByteOrder binary.ByteOrder
+
+	// Network selects the encoding used by the Bedrock Edition network
+	// protocol, usually together with binary.LittleEndian: Int and Long
+	// payloads and all lengths are zigzag varints, except string lengths,
+	// which are unsigned varints.
+	Network bool
+
+	// RawStrings reads strings as standard UTF-8, as Bedrock Edition writes
+	// them, instead of Java's modified UTF-8.
+	RawStrings bool
+
+	// NamelessRoot reads the root tag without a name, as sent by the Java
+	// Edition network protocol since 1.20.2.
+	NamelessRoot bool
+
+	// DisallowEndRoot makes Decode fail on a root tag of type End, which the
+	// network protocols use to mean that no NBT is present.
+	DisallowEndRoot bool
+
+	// OrderedCompounds decodes compounds as *OrderedCompound instead of
+	// Compound, so that encoding them again preserves the order of tags.
+	OrderedCompounds bool
+
+	// MaxDepth limits how deeply lists and compounds may be nested.
+	// Defaults to DefaultMaxDepth; a negative value disables the limit.
+	MaxDepth int
+
+	// MaxBytes limits the total number of bytes read by the decoder, over
+	// all root tags. Zero means no limit.
+	MaxBytes int64
+
+	// MaxArrayLength limits the number of elements of each array and list.
+	// Zero means no limit.
+	MaxArrayLength int
 }
 
+// NewDecoder returns a Decoder reading from r. The Decoder buffers its input,
+// so it may read past the end of the NBT data. The bytes read ahead are
+// available from Buffered.
 func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r: &offsetReader{r: r}}
+	return NewDecoderOptions(r, DecoderOptions{})
 }
 
+func NewDecoderOptions(r io.Reader, opts DecoderOptions) *Decoder {
+	if opts.ByteOrder == nil {
+		opts.ByteOrder = binary.BigEndian
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	dec := &Decoder{
+		r:               &offsetReader{r: r, buf: make([]byte, decoderBufferSize), max: opts.MaxBytes},
+		order:           opts.ByteOrder,
+		network:         opts.Network,
+		raw:             opts.RawStrings,
+		namelessRoot:    opts.NamelessRoot,
+		disallowEndRoot: opts.DisallowEndRoot,
+		ordered:         opts.OrderedCompounds,
+		maxDepth:        opts.MaxDepth,
+		maxArrayLength:  opts.MaxArrayLength,
+	}
+	dec.bigEndian = opts.ByteOrder == binary.BigEndian
+	dec.tokens = &Reader{dec: dec}
+	return dec
+}
+
+// Buffered returns a reader of the data read from the input of the Decoder but
+// not yet decoded. The reader is valid until the next call to Decode.
+func (dec *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(dec.r.buf[dec.r.pos:dec.r.end])
+}
+
+// decoderBufferSize is the size of the buffer through which the Decoder reads
+// its input, and so the most it may read past the end of the NBT data.
+const decoderBufferSize = 8192
+
+// offsetReader buffers the input of a Decoder and counts the bytes consumed
+// from it.
 type offsetReader struct {
-	r      io.Reader
-	offset int64
+	r        io.Reader
+	buf      []byte
+	pos, end int
+
+	offset int64 // of buf[pos] in the input
+	max    int64
+	err    error
+
+	// record holds the bytes consumed while recording is set
+	record    []byte
+	recording bool
 }
 
-func (r *offsetReader) Read(p []byte) (n int, err error) {
-	n, err = r.r.Read(p)
+// consume advances past the next n buffered bytes.
+func (r *offsetReader) consume(n int) {
+	if r.recording {
+		r.record = append(r.record, r.buf[r.pos:r.pos+n]...)
+	}
+	r.pos += n
 	r.offset += int64(n)
-	return n, err
+}
+
+// fill reads more input into the buffer, moving the unconsumed bytes to its
+// start. The number of bytes read from r never exceeds max.
+func (r *offsetReader) fill() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if r.pos > 0 {
+		r.end = copy(r.buf, r.buf[r.pos:r.end])
+		r.pos = 0
+	}
+
+	p := r.buf[r.end:]
+	if r.max > 0 {
+		if rem := r.max - r.offset - int64(r.end); int64(len(p)) > rem {
+			p = p[:rem]
+		}
+		if len(p) == 0 {
+			return &LimitError{"bytes", r.max}
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		n, err := r.r.Read(p)
+		r.end += n
+		if err != nil {
+			r.err = err
+		}
+		if n > 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return io.ErrNoProgress
+}
+
+// next consumes the next n bytes, which must fit in the buffer, and returns
+// them. They are only valid until the next read.
+func (r *offsetReader) next(n int) ([]byte, error) {
+	for r.end-r.pos < n {
+		if err := r.fill(); err != nil {
+			if err == io.EOF && r.end > r.pos {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+
+	b := r.buf[r.pos : r.pos+n]
+	r.consume(n)
+	return b, nil
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	if r.pos == r.end {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf[r.pos:r.end])
+	r.consume(n)
+	return n, nil
+}
+
+// discard consumes n bytes, seeking over them if they are not buffered and
+// the input is an io.Seeker.
+func (r *offsetReader) discard(n int64) error {
+	for n > 0 {
+		if r.pos == r.end {
+			if n > int64(len(r.buf)) && r.seek(n) {
+				return nil
+			}
+
+			if err := r.fill(); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return err
+			}
+		}
+
+		k := n
+		if rem := int64(r.end - r.pos); k > rem {
+			k = rem
+		}
+		r.consume(int(k))
+		n -= k
+	}
+	return nil
+}
+
+// seek skips n bytes of the input, which must have no buffered bytes left,
+// and reports whether it succeeded. Seeking past the end of the input is not
+// an error, but the next read then fails.
+func (r *offsetReader) seek(n int64) bool {
+	s, ok := r.r.(io.Seeker)
+	if !ok || r.err != nil || r.recording || r.max > 0 && r.offset+n > r.max {
+		return false
+	}
+
+	if _, err := s.Seek(n, io.SeekCurrent); err != nil {
+		return false
+	}
+	r.offset += n
+	return true
+}
+
+func (r *offsetReader) ReadByte() (byte, error) {
+	if r.pos == r.end {
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	c := r.buf[r.pos]
+	r.consume(1)
+	return c, nil
 }
 
 func (dec *Decoder) Decode() (*NamedTag, error) {
-	return dec.readNamedTag()
+	tok, err := dec.tokens.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.Type == TypeEnd {
+		return &NamedTag{}, nil
+	}
+
+	payload, err := dec.decodePayload()
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamedTag{tok.Type, tok.Name, payload}, nil
+}
+
+// decodePayload builds the payload starting at the next token.
+func (dec *Decoder) decodePayload() (interface{}, error) {
+	tok, err := dec.tokens.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.Kind {
+	case TokenValue:
+		return tok.Value, nil
+	case TokenArrayStart:
+		return dec.decodeArray(tok)
+	case TokenListStart:
+		return dec.decodeList(tok)
+	case TokenCompoundStart:
+		return dec.decodeCompound()
+	default:
+		return nil, dec.errorf("unexpected token (%v)", tok.Kind)
+	}
+}
+
+// maxPrealloc is the largest number of elements allocated for an array, list
+// or string before they are read, so that a false length fails at the end of
+// the input instead of allocating as much memory as it claims.
+const maxPrealloc = readerChunkSize
+
+func prealloc(length int) int {
+	if length > maxPrealloc {
+		return maxPrealloc
+	}
+	return length
+}
+
+// grow returns how many more elements to allocate for an array or list of
+// length elements, n of which have been read, at most doubling its size.
+func grow(n, length int) int {
+	k := length - n
+	if k > n && k > maxPrealloc {
+		k = n
+		if k < maxPrealloc {
+			k = maxPrealloc
+		}
+	}
+	return k
+}
+
+// decodeArray reads the elements of an array directly into the result rather
+// than through array chunk tokens.
+func (dec *Decoder) decodeArray(start Token) (interface{}, error) {
+	array, err := dec.decodeNumbers(start.Type, start.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	// TokenArrayEnd
+	if _, err := dec.tokens.Next(); err != nil {
+		return nil, err
+	}
+
+	return array, nil
+}
+
+func (dec *Decoder) decodeList(start Token) (*List, error) {
+	l := new(List)
+	if start.Elem != TypeEnd {
+		var (
+			array interface{}
+			err   error
+		)
+		if _, ok := payloadSizes[start.Elem]; ok {
+			array, err = dec.decodeNumbers(start.Elem, start.Length)
+		} else {
+			array, err = dec.decodeElements(start.Elem, start.Length)
+		}
+		if err != nil {
+			return nil, err
+		}
+		*l = List{start.Elem, array}
+	}
+
+	// TokenListEnd
+	if _, err := dec.tokens.Next(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// decodeNumbers reads the length elements of the current array or list of
+// numbers in bulk, bypassing the tokens. The slice grows as it is read, so
+// that a false length fails before it is fully allocated.
+func (dec *Decoder) decodeNumbers(typ Type, length int) (interface{}, error) {
+	var (
+		array interface{}
+		err   error
+		n     = prealloc(length)
+	)
+	switch typ {
+	case TypeByteArray:
+		a := make([]byte, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]byte, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeByte:
+		a := make([]int8, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]int8, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeShort:
+		a := make([]int16, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]int16, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeInt, TypeIntArray:
+		a := make([]int32, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]int32, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeLong, TypeLongArray:
+		a := make([]int64, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]int64, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeFloat:
+		a := make([]float32, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]float32, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	case TypeDouble:
+		a := make([]float64, 0, n)
+		for len(a) < length && err == nil {
+			k := grow(len(a), length)
+			a = append(a, make([]float64, k)...)
+			err = dec.readNumbers(a[len(a)-k:])
+		}
+		array = a
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec.tokens.stack[len(dec.tokens.stack)-1].remaining = 0
+	return array, nil
+}
+
+// decodeElements reads the elements of a list of any other type through
+// tokens.
+func (dec *Decoder) decodeElements(elem Type, length int) (interface{}, error) {
+	var (
+		bytes    [][]byte
+		strings  []string
+		lists    []*List
+		maps     []Compound
+		ordered  []*OrderedCompound
+		ints     [][]int32
+		longs    [][]int64
+		capacity = prealloc(length)
+	)
+	switch {
+	case elem == TypeByteArray:
+		bytes = make([][]byte, 0, capacity)
+	case elem == TypeString:
+		strings = make([]string, 0, capacity)
+	case elem == TypeList:
+		lists = make([]*List, 0, capacity)
+	case elem == TypeCompound && dec.ordered:
+		ordered = make([]*OrderedCompound, 0, capacity)
+	case elem == TypeCompound:
+		maps = make([]Compound, 0, capacity)
+	case elem == TypeIntArray:
+		ints = make([][]int32, 0, capacity)
+	case elem == TypeLongArray:
+		longs = make([][]int64, 0, capacity)
+	}
+
+	for i := 0; i < length; i++ {
+		payload, ok, err := dec.decodeSelected(elem)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		switch p := payload.(type) {
+		case []byte:
+			bytes = append(bytes, p)
+		case string:
+			strings = append(strings, p)
+		case *List:
+			lists = append(lists, p)
+		case Compound:
+			maps = append(maps, p)
+		case *OrderedCompound:
+			ordered = append(ordered, p)
+		case []int32:
+			ints = append(ints, p)
+		case []int64:
+			longs = append(longs, p)
+		}
+	}
+
+	switch {
+	case elem == TypeByteArray:
+		return bytes, nil
+	case elem == TypeString:
+		return strings, nil
+	case elem == TypeList:
+		return lists, nil
+	case elem == TypeCompound && dec.ordered:
+		return ordered, nil
+	case elem == TypeCompound:
+		return maps, nil
+	case elem == TypeIntArray:
+		return ints, nil
+	default:
+		return longs, nil
+	}
+}
+
+func (dec *Decoder) decodeCompound() (interface{}, error) {
+	if dec.ordered {
+		return dec.decodeOrderedCompound()
+	}
+
+	m := make(Compound)
+	for {
+		tok, err := dec.tokens.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.Kind == TokenCompoundEnd {
+			return m, nil
+		}
+
+		payload, ok, err := dec.decodeSelected(tok.Type)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		if _, exists := m[tok.Name]; exists {
+			return nil, dec.errorf("duplicate name (%q)", tok.Name)
+		}
+		m[tok.Name] = &Tag{tok.Type, payload}
+	}
+}
+
+func (dec *Decoder) decodeOrderedCompound() (*OrderedCompound, error) {
+	c := new(OrderedCompound)
+	for {
+		tok, err := dec.tokens.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.Kind == TokenCompoundEnd {
+			return c, nil
+		}
+
+		payload, ok, err := dec.decodeSelected(tok.Type)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		if c.Get(tok.Name) != nil {
+			return nil, dec.errorf("duplicate name (%q)", tok.Name)
+		}
+		c.Set(tok.Name, &Tag{tok.Type, payload})
+	}
 }
 
 func (dec *Decoder) wrap(err error) error {
 	if err != nil {
-		return &DecodeError{dec.r.offset, errors.WithStack(err)}
+		return &DecodeError{Offset: dec.r.offset, Path: dec.tokens.path(), Err: errors.WithStack(err)}
 	}
 	return nil
 }
@@ -44,7 +588,12 @@ func (dec *Decoder) errorf(format string, a ...interface{}) error {
 
 type DecodeError struct {
 	Offset int64
-	Err    error
+
+	// Path of the tag being read, in the same form as EncodeError.Path. It
+	// is empty at the root and does not extend into tags being skipped.
+	Path string
+
+	Err error
 }
 
 func (e *DecodeError) Error() string {
@@ -52,7 +601,9 @@ func (e *DecodeError) Error() string {
 }
 
 func (e *DecodeError) Format(f fmt.State, c rune) {
-	if f.Flag('+') {
+	if f.Flag('+') && e.Path != "" {
+		fmt.Fprintf(f, "offset %d at %s: %+v", e.Offset, e.Path, e.Err)
+	} else if f.Flag('+') {
 		fmt.Fprintf(f, "offset %d: %+v", e.Offset, e.Err)
 	} else {
 		fmt.Fprint(f, e.Err)
@@ -63,63 +614,90 @@ func (e *DecodeError) Cause() error {
 	return e.Err
 }
 
-func readBE(r io.Reader, v interface{}) error {
-	return binary.Read(r, binary.BigEndian, v)
+// LimitError is the cause of a DecodeError when the input exceeds one of the
+// limits set in DecoderOptions.
+type LimitError struct {
+	Limit string // "depth", "bytes" or "array length"
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded (%d)", e.Limit, e.Max)
 }
 
-func (dec *Decoder) readNamedTag() (*NamedTag, error) {
-	typ, err := dec.readType()
+func (dec *Decoder) readType() (Type, error) {
+	c, err := dec.r.ReadByte()
 	if err != nil {
-		return nil, err
+		return TypeEnd, dec.wrap(err)
 	}
+	return Type(c), nil
+}
 
-	if typ == TypeEnd {
-		return &NamedTag{}, nil
+func (dec *Decoder) readUint16() (uint16, error) {
+	b, err := dec.r.next(2)
+	if err != nil {
+		return 0, dec.wrap(err)
+	}
+	if dec.bigEndian {
+		return binary.BigEndian.Uint16(b), nil
 	}
+	return dec.order.Uint16(b), nil
+}
 
-	name, err := dec.readString()
+func (dec *Decoder) readUint32() (uint32, error) {
+	b, err := dec.r.next(4)
 	if err != nil {
-		return nil, err
+		return 0, dec.wrap(err)
+	}
+	if dec.bigEndian {
+		return binary.BigEndian.Uint32(b), nil
 	}
+	return dec.order.Uint32(b), nil
+}
 
-	var payload interface{}
+func (dec *Decoder) readUint64() (uint64, error) {
+	b, err := dec.r.next(8)
+	if err != nil {
+		return 0, dec.wrap(err)
+	}
+	if dec.bigEndian {
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return dec.order.Uint64(b), nil
+}
+
+func (dec *Decoder) checkType(typ Type) error {
+	if typ > TypeLongArray {
+		return dec.errorf("unknown type (%v)", typ)
+	}
+	return nil
+}
+
+func (dec *Decoder) readScalar(typ Type) (payload interface{}, err error) {
 	switch typ {
 	case TypeByte:
-		var n int8
-		err = dec.wrap(readBE(dec.r, &n))
-		payload = n
+		var c byte
+		c, err = dec.r.ReadByte()
+		err = dec.wrap(err)
+		payload = int8(c)
 	case TypeShort:
-		var n int16
-		err = dec.wrap(readBE(dec.r, &n))
-		payload = n
+		var u uint16
+		u, err = dec.readUint16()
+		payload = int16(u)
 	case TypeInt:
-		var n int32
-		err = dec.wrap(readBE(dec.r, &n))
-		payload = n
+		payload, err = dec.readInt32()
 	case TypeLong:
-		var n int64
-		err = dec.wrap(readBE(dec.r, &n))
-		payload = n
+		payload, err = dec.readInt64()
 	case TypeFloat:
-		var x float32
-		err = dec.wrap(readBE(dec.r, &x))
-		payload = x
+		var u uint32
+		u, err = dec.readUint32()
+		payload = math.Float32frombits(u)
 	case TypeDouble:
-		var x float64
-		err = dec.wrap(readBE(dec.r, &x))
-		payload = x
-	case TypeByteArray:
-		payload, err = dec.readByteArray()
+		var u uint64
+		u, err = dec.readUint64()
+		payload = math.Float64frombits(u)
 	case TypeString:
 		payload, err = dec.readString()
-	case TypeList:
-		payload, err = dec.readList()
-	case TypeCompound:
-		payload, err = dec.readCompound()
-	case TypeIntArray:
-		payload, err = dec.readIntArray()
-	case TypeLongArray:
-		payload, err = dec.readLongArray()
 	default:
 		err = dec.errorf("unknown type (%v)", typ)
 	}
@@ -128,194 +706,225 @@ func (dec *Decoder) readNamedTag() (*NamedTag, error) {
 		return nil, err
 	}
 
-	return &NamedTag{typ, name, payload}, nil
+	return payload, nil
 }
 
-func (dec *Decoder) readType() (Type, error) {
-	var typ Type
-	err := dec.wrap(readBE(dec.r, &typ))
-	return typ, err
-}
+func (dec *Decoder) readInt32() (int32, error) {
+	if !dec.network {
+		u, err := dec.readUint32()
+		return int32(u), err
+	}
 
-func (dec *Decoder) readByteArray() ([]byte, error) {
-	length, err := dec.readLength()
+	u, err := binary.ReadUvarint(dec.r)
 	if err != nil {
-		return nil, err
+		return 0, dec.wrap(err)
 	}
 
-	b := make([]byte, length)
-	if err := readBE(dec.r, b); err != nil {
-		return nil, dec.wrap(err)
+	if u > math.MaxUint32 {
+		return 0, dec.errorf("varint overflows int32 (%d)", u)
 	}
 
-	return b, nil
+	return int32(u>>1) ^ -int32(u&1), nil
 }
 
-func (dec *Decoder) readLength() (int32, error) {
-	var length int32
-	err := dec.wrap(readBE(dec.r, &length))
-	if length < 0 {
-		err = dec.errorf("negative length (%d)", length)
+func (dec *Decoder) readInt64() (int64, error) {
+	if !dec.network {
+		u, err := dec.readUint64()
+		return int64(u), err
 	}
-	return length, err
+
+	n, err := binary.ReadVarint(dec.r)
+	return n, dec.wrap(err)
 }
 
-func (dec *Decoder) readString() (string, error) {
-	var length int16
-	if err := readBE(dec.r, &length); err != nil {
-		return "", dec.wrap(err)
+// readNumbers reads a slice of numeric payloads.
+func (dec *Decoder) readNumbers(a interface{}) error {
+	if dec.network {
+		var err error
+		switch a := a.(type) {
+		case []int32:
+			for i := range a {
+				if a[i], err = dec.readInt32(); err != nil {
+					return err
+				}
+			}
+			return nil
+		case []int64:
+			for i := range a {
+				if a[i], err = dec.readInt64(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
 	}
 
-	if length < 0 {
-		return "", dec.errorf("negative length (%d)", length)
+	switch a := a.(type) {
+	case []byte:
+		return dec.readElems(len(a), 1, func(b []byte, i int) {
+			copy(a[i:], b)
+		})
+	case []int8:
+		return dec.readElems(len(a), 1, func(b []byte, i int) {
+			for _, c := range b {
+				a[i] = int8(c)
+				i++
+			}
+		})
+	case []int16:
+		return dec.readElems(len(a), 2, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 2 {
+				a[i] = int16(dec.order.Uint16(b[j:]))
+				i++
+			}
+		})
+	case []int32:
+		return dec.readElems(len(a), 4, func(b []byte, i int) {
+			if dec.bigEndian {
+				for j := 0; j < len(b); j += 4 {
+					a[i] = int32(binary.BigEndian.Uint32(b[j:]))
+					i++
+				}
+				return
+			}
+			for j := 0; j < len(b); j += 4 {
+				a[i] = int32(dec.order.Uint32(b[j:]))
+				i++
+			}
+		})
+	case []int64:
+		return dec.readElems(len(a), 8, func(b []byte, i int) {
+			if dec.bigEndian {
+				for j := 0; j < len(b); j += 8 {
+					a[i] = int64(binary.BigEndian.Uint64(b[j:]))
+					i++
+				}
+				return
+			}
+			for j := 0; j < len(b); j += 8 {
+				a[i] = int64(dec.order.Uint64(b[j:]))
+				i++
+			}
+		})
+	case []float32:
+		return dec.readElems(len(a), 4, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 4 {
+				a[i] = math.Float32frombits(dec.order.Uint32(b[j:]))
+				i++
+			}
+		})
+	case []float64:
+		return dec.readElems(len(a), 8, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 8 {
+				a[i] = math.Float64frombits(dec.order.Uint64(b[j:]))
+				i++
+			}
+		})
+	default:
+		return dec.errorf("unsupported numbers (%T)", a)
 	}
+}
 
-	b := make([]byte, length)
-	if err := readBE(dec.r, b); err != nil {
-		return "", dec.wrap(err)
-	}
+// readElems reads n elements of the given size in pieces that fit in the
+// buffer, passing each to f along with the index of its first element.
+func (dec *Decoder) readElems(n, size int, f func(b []byte, i int)) error {
+	for i := 0; i < n; {
+		k := n - i
+		if max := len(dec.r.buf) / size; k > max {
+			k = max
+		}
 
-	return string(b), nil
-}
+		b, err := dec.r.next(k * size)
+		if err != nil {
+			return dec.wrap(err)
+		}
 
-func (dec *Decoder) readList() (*List, error) {
-	typ, err := dec.readType()
-	if err != nil {
-		return nil, err
+		f(b, i)
+		i += k
 	}
+	return nil
+}
 
-	length, err := dec.readLength()
+// readLength reads the length of an array or list.
+func (dec *Decoder) readLength() (int32, error) {
+	length, err := dec.readInt32()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	if typ == TypeEnd {
-		return &List{}, nil
-	}
-
-	var array interface{}
-	if typ < TypeByteArray {
-		switch typ {
-		case TypeByte:
-			array = make([]int8, length)
-		case TypeShort:
-			array = make([]int16, length)
-		case TypeInt:
-			array = make([]int32, length)
-		case TypeLong:
-			array = make([]int64, length)
-		case TypeFloat:
-			array = make([]float32, length)
-		case TypeDouble:
-			array = make([]float64, length)
-		}
-
-		if err := readBE(dec.r, array); err != nil {
-			return nil, dec.wrap(err)
-		}
-
-		return &List{typ, array}, nil
+	if length < 0 {
+		return 0, dec.errorf("negative length (%d)", length)
 	}
 
-	switch typ {
-	case TypeByteArray:
-		a := make([][]byte, length)
-		for i := range a {
-			if a[i], err = dec.readByteArray(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	case TypeString:
-		a := make([]string, length)
-		for i := range a {
-			if a[i], err = dec.readString(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	case TypeList:
-		a := make([]*List, length)
-		for i := range a {
-			if a[i], err = dec.readList(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	case TypeCompound:
-		a := make([]Compound, length)
-		for i := range a {
-			if a[i], err = dec.readCompound(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	case TypeIntArray:
-		a := make([][]int32, length)
-		for i := range a {
-			if a[i], err = dec.readIntArray(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	case TypeLongArray:
-		a := make([][]int64, length)
-		for i := range a {
-			if a[i], err = dec.readLongArray(); err != nil {
-				return nil, err
-			}
-		}
-		array = a
-	default:
-		return nil, dec.errorf("unknown type (%v)", typ)
+	if max := dec.maxArrayLength; max > 0 && int(length) > max {
+		return 0, dec.wrap(&LimitError{"array length", int64(max)})
 	}
 
-	return &List{typ, array}, nil
+	return length, nil
 }
 
-func (dec *Decoder) readCompound() (Compound, error) {
-	m := make(Compound)
-	for {
-		tag, err := dec.readNamedTag()
+func (dec *Decoder) readStringLength() (int64, error) {
+	if dec.network {
+		u, err := binary.ReadUvarint(dec.r)
 		if err != nil {
-			return nil, err
+			return 0, dec.wrap(err)
 		}
 
-		if tag.Type == TypeEnd {
-			return m, nil
+		if u > math.MaxInt32 {
+			return 0, dec.errorf("length overflows int32 (%d)", u)
 		}
+		return int64(u), nil
+	}
 
-		if _, exists := m[tag.Name]; exists {
-			return nil, dec.errorf("duplicate name (%q)", tag.Name)
-		}
-		m[tag.Name] = &Tag{tag.Type, tag.Payload}
+	n, err := dec.readUint16()
+	return int64(n), err
+}
+
+func (dec *Decoder) skipString() error {
+	length, err := dec.readStringLength()
+	if err != nil {
+		return err
 	}
+	return dec.tokens.discard(length)
 }
 
-func (dec *Decoder) readIntArray() ([]int32, error) {
-	length, err := dec.readLength()
+func (dec *Decoder) readString() (string, error) {
+	length, err := dec.readStringLength()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	a := make([]int32, length)
-	if err := readBE(dec.r, a); err != nil {
-		return nil, dec.wrap(err)
+	b, err := dec.readBytes(length)
+	if err != nil {
+		return "", err
+	}
+
+	if dec.raw {
+		return string(b), nil
+	}
+
+	s, ok := decodeMUTF8(b)
+	if !ok {
+		return "", dec.errorf("invalid modified UTF-8 (%q)", b)
 	}
 
-	return a, nil
+	return s, nil
 }
 
-func (dec *Decoder) readLongArray() ([]int64, error) {
-	length, err := dec.readLength()
-	if err != nil {
-		return nil, err
+// readBytes reads n bytes, which are only valid until the next read if they
+// fit in the buffer.
+func (dec *Decoder) readBytes(n int64) ([]byte, error) {
+	if n <= int64(len(dec.r.buf)) {
+		b, err := dec.r.next(int(n))
+		return b, dec.wrap(err)
 	}
 
-	a := make([]int64, length)
-	if err := readBE(dec.r, a); err != nil {
+	buf := bytes.NewBuffer(make([]byte, 0, maxPrealloc))
+	if _, err := io.CopyN(buf, dec.r, n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
 		return nil, dec.wrap(err)
 	}
-
-	return a, nil
+	return buf.Bytes(), nil
 }
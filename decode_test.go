@@ -2,9 +2,15 @@ package nbt
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
 )
 
 func TestDecoder(t *testing.T) {
@@ -17,3 +23,277 @@ func TestDecoder(t *testing.T) {
 		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
 	}
 }
+
+func TestDecoderLittleEndian(t *testing.T) {
+	dec := NewDecoderOptions(bytes.NewReader(testDataLE), DecoderOptions{ByteOrder: binary.LittleEndian})
+	tag, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(testTag, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestDecoderNetwork(t *testing.T) {
+	dec := NewDecoderOptions(bytes.NewReader(testDataNetwork), DecoderOptions{ByteOrder: binary.LittleEndian, Network: true})
+	tag, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(testTag, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestDecoderNamelessRoot(t *testing.T) {
+	data := append([]byte{testData[0]}, testData[7:]...)
+	tag, err := NewDecoderOptions(bytes.NewReader(data), DecoderOptions{NamelessRoot: true}).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &NamedTag{testTag.Type, "", testTag.Payload}
+	if diff := cmp.Diff(expected, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestDecoderEndRoot(t *testing.T) {
+	data := []byte{byte(TypeEnd)}
+
+	tag, err := NewDecoderOptions(bytes.NewReader(data), DecoderOptions{NamelessRoot: true}).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&NamedTag{}, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	_, err = NewDecoderOptions(bytes.NewReader(data), DecoderOptions{DisallowEndRoot: true}).Decode()
+	if err == nil {
+		t.Fatal("expected error for End root")
+	}
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	var data []byte
+	data = append(data, testData...)
+	data = append(data, testData...)
+	data = append(data, "trailing"...)
+
+	// a reader that is not a bytes.Reader, as a packet stream would be
+	r := io.MultiReader(bytes.NewReader(data))
+	dec := NewDecoder(r)
+	for i := 0; i < 2; i++ {
+		tag, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(testTag, tag); diff != "" {
+			t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+		}
+	}
+
+	rest, err := ioutil.ReadAll(io.MultiReader(dec.Buffered(), r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "trailing" {
+		t.Fatalf("expected trailing data, got %q", rest)
+	}
+}
+
+// nestedLists returns a root list containing n levels of nested lists.
+func nestedLists(n int) []byte {
+	data := []byte{byte(TypeList), 0, 0}
+	for i := 0; i < n; i++ {
+		data = append(data, byte(TypeList), 0, 0, 0, 1)
+	}
+	return append(data, byte(TypeEnd), 0, 0, 0, 0)
+}
+
+func TestDecoderLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		opts  DecoderOptions
+		limit string
+	}{
+		{"depth", nestedLists(DefaultMaxDepth), DecoderOptions{}, "depth"},
+		{"depth option", nestedLists(10), DecoderOptions{MaxDepth: 10}, "depth"},
+		{"bytes", testData, DecoderOptions{MaxBytes: int64(len(testData) - 1)}, "bytes"},
+		{"array length", testData, DecoderOptions{MaxArrayLength: 2}, "array length"},
+	}
+
+	for _, test := range tests {
+		_, err := NewDecoderOptions(bytes.NewReader(test.data), test.opts).Decode()
+		if e, ok := errors.Cause(err).(*LimitError); !ok || e.Limit != test.limit {
+			t.Errorf("%s: expected %s limit error, got %v", test.name, test.limit, err)
+		}
+
+		r := NewReaderOptions(bytes.NewReader(test.data), test.opts)
+		if _, err := r.Next(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		err = r.Skip()
+		if e, ok := errors.Cause(err).(*LimitError); !ok || e.Limit != test.limit {
+			t.Errorf("%s: expected %s limit error skipping, got %v", test.name, test.limit, err)
+		}
+	}
+
+	valid := []struct {
+		name string
+		data []byte
+		opts DecoderOptions
+	}{
+		{"depth", nestedLists(DefaultMaxDepth - 1), DecoderOptions{}},
+		{"no depth limit", nestedLists(DefaultMaxDepth), DecoderOptions{MaxDepth: -1}},
+		{"bytes", testData, DecoderOptions{MaxBytes: int64(len(testData))}},
+	}
+
+	for _, test := range valid {
+		if _, err := NewDecoderOptions(bytes.NewReader(test.data), test.opts).Decode(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestDecoderFalseLength(t *testing.T) {
+	tests := map[string][]byte{
+		"byte array": {byte(TypeByteArray), 0, 0, 0x7f, 0xff, 0xff, 0xff, 1, 2, 3},
+		"long array": {byte(TypeLongArray), 0, 0, 0x7f, 0xff, 0xff, 0xff, 1, 2, 3},
+		"list":       {byte(TypeList), 0, 0, byte(TypeLong), 0x7f, 0xff, 0xff, 0xff, 1, 2, 3},
+	}
+
+	for name, data := range tests {
+		_, err := NewDecoder(bytes.NewReader(data)).Decode()
+		if errors.Cause(err) != io.ErrUnexpectedEOF {
+			t.Errorf("%s: expected unexpected EOF, got %v", name, err)
+		}
+	}
+
+	// network string lengths are not limited to 16 bits
+	data := []byte{byte(TypeString), 0, 0xff, 0xff, 0xff, 0xff, 0x07, 'a'}
+	_, err := NewDecoderOptions(bytes.NewReader(data), DecoderOptions{Network: true}).Decode()
+	if errors.Cause(err) != io.ErrUnexpectedEOF {
+		t.Errorf("string: expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestDecodeErrorPath(t *testing.T) {
+	sections := make([]Compound, 5)
+	for i := range sections {
+		sections[i] = Compound{"BlockStates": &Tag{TypeLongArray, make([]int64, 4)}}
+	}
+	tag := &NamedTag{TypeCompound, "", Compound{
+		"Level": &Tag{TypeCompound, Compound{
+			"Sections": &Tag{TypeList, &List{TypeCompound, sections}},
+		}},
+	}}
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(tag); err != nil {
+		t.Fatal(err)
+	}
+
+	// cut into the last long array, before the ends of three compounds
+	data := buf.Bytes()[:buf.Len()-3-4]
+
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	e, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+
+	if e.Path != "Level.Sections[4].BlockStates" {
+		t.Fatalf("unexpected path %q", e.Path)
+	}
+
+	expected := fmt.Sprintf("offset %d at Level.Sections[4].BlockStates: unexpected EOF", e.Offset)
+	if got := fmt.Sprintf("%+v", err); !strings.HasPrefix(got, expected) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+// benchChunk returns a tag shaped like a chunk, with many small tags as well
+// as large arrays.
+func benchChunk() *NamedTag {
+	sections := make([]Compound, 16)
+	for i := range sections {
+		palette := make([]Compound, 8)
+		for j := range palette {
+			palette[j] = Compound{
+				"Name": &Tag{TypeString, "minecraft:stone"},
+				"Properties": &Tag{TypeCompound, Compound{
+					"axis": &Tag{TypeString, "y"},
+				}},
+			}
+		}
+
+		states := make([]int64, 256)
+		for j := range states {
+			states[j] = int64(i*j) * 0x0123456789abcdef
+		}
+
+		sections[i] = Compound{
+			"Y":           &Tag{TypeByte, int8(i)},
+			"Palette":     &Tag{TypeList, &List{TypeCompound, palette}},
+			"BlockStates": &Tag{TypeLongArray, states},
+			"BlockLight":  &Tag{TypeByteArray, make([]byte, 2048)},
+			"SkyLight":    &Tag{TypeByteArray, make([]byte, 2048)},
+		}
+	}
+
+	entities := make([]Compound, 32)
+	for i := range entities {
+		entities[i] = Compound{
+			"id":     &Tag{TypeString, "minecraft:chest"},
+			"x":      &Tag{TypeInt, int32(i)},
+			"y":      &Tag{TypeInt, int32(64)},
+			"z":      &Tag{TypeInt, int32(-i)},
+			"Pos":    &Tag{TypeList, &List{TypeDouble, []float64{1.5, 64, -2.5}}},
+			"Motion": &Tag{TypeList, &List{TypeDouble, []float64{0, 0, 0}}},
+			"Items":  &Tag{TypeList, &List{TypeEnd, nil}},
+		}
+	}
+
+	return &NamedTag{TypeCompound, "", Compound{
+		"DataVersion": &Tag{TypeInt, int32(2586)},
+		"Level": &Tag{TypeCompound, Compound{
+			"xPos":         &Tag{TypeInt, int32(0)},
+			"zPos":         &Tag{TypeInt, int32(0)},
+			"LastUpdate":   &Tag{TypeLong, int64(123456789)},
+			"Status":       &Tag{TypeString, "full"},
+			"Biomes":       &Tag{TypeIntArray, make([]int32, 1024)},
+			"Sections":     &Tag{TypeList, &List{TypeCompound, sections}},
+			"TileEntities": &Tag{TypeList, &List{TypeCompound, entities}},
+		}},
+	}}
+}
+
+func benchmarkDecoder(b *testing.B, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	benchmarkDecoder(b, testData)
+}
+
+func BenchmarkDecoderChunk(b *testing.B) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(benchChunk()); err != nil {
+		b.Fatal(err)
+	}
+	benchmarkDecoder(b, buf.Bytes())
+}
@@ -0,0 +1,340 @@
+package nbt
+
+import (
+	"sort"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	// ChangeAdded is a tag of a compound only present in the new tree.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved is a tag of a compound only present in the old tree.
+	ChangeRemoved
+	// ChangeType is a tag whose type changed.
+	ChangeType
+	// ChangeValue is a tag whose value changed. Arrays, and lists whose
+	// element type changed, are compared as a whole.
+	ChangeValue
+	// ChangeInserted is a list element inserted in the new tree.
+	ChangeInserted
+	// ChangeDeleted is a list element deleted from the old tree.
+	ChangeDeleted
+)
+
+var changeKindNames = []string{
+	ChangeAdded:    "added",
+	ChangeRemoved:  "removed",
+	ChangeType:     "type changed",
+	ChangeValue:    "value changed",
+	ChangeInserted: "inserted",
+	ChangeDeleted:  "deleted",
+}
+
+func (kind ChangeKind) String() string {
+	if kind < 0 || int(kind) >= len(changeKindNames) {
+		return "unknown"
+	}
+	return changeKindNames[kind]
+}
+
+// Change is a difference between two trees found by Diff.
+type Change struct {
+	Kind ChangeKind
+
+	// Path of the tag in the syntax of ParsePath. The indices of removed
+	// and deleted tags refer to the old tree, and the others to the new.
+	Path string
+
+	// Old and New are the tag before and after the change, or nil if it was
+	// added or removed.
+	Old, New *Tag
+}
+
+// String formats the change as a line such as
+//
+//	~ Data.Player.Pos[1]: 64d -> 65d
+//
+// beginning with + for added tags, - for removed tags, ! for type changes
+// and ~ for value changes.
+func (c Change) String() string {
+	var b strings.Builder
+	switch c.Kind {
+	case ChangeAdded, ChangeInserted:
+		b.WriteString("+ ")
+	case ChangeRemoved, ChangeDeleted:
+		b.WriteString("- ")
+	case ChangeType:
+		b.WriteString("! ")
+	default:
+		b.WriteString("~ ")
+	}
+
+	if c.Path == "" {
+		b.WriteString("(root)")
+	} else {
+		b.WriteString(c.Path)
+	}
+	b.WriteString(": ")
+
+	switch c.Kind {
+	case ChangeAdded, ChangeInserted:
+		b.WriteString(formatChangeTag(c.New, false))
+	case ChangeRemoved, ChangeDeleted:
+		b.WriteString(formatChangeTag(c.Old, false))
+	default:
+		typed := c.Kind == ChangeType
+		b.WriteString(formatChangeTag(c.Old, typed))
+		b.WriteString(" -> ")
+		b.WriteString(formatChangeTag(c.New, typed))
+	}
+	return b.String()
+}
+
+func formatChangeTag(tag *Tag, typed bool) string {
+	if tag == nil {
+		return "<nil>"
+	}
+
+	s, err := FormatSNBT(tag.Type, tag.Payload)
+	if err != nil {
+		s = "<" + err.Error() + ">"
+	}
+	if typed {
+		s += " (" + tag.Type.String() + ")"
+	}
+	return s
+}
+
+// FormatDiff formats changes one per line, as described for Change.String.
+func FormatDiff(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Diff returns the changes from the payload of a to that of b, in the order
+// of their paths with compounds sorted by name. The names of a and b are not
+// compared. Tags are compared as by Equal.
+func Diff(a, b *NamedTag) []Change {
+	d := new(differ)
+	d.payload("", &Tag{a.Type, a.Payload}, &Tag{b.Type, b.Payload})
+	return d.changes
+}
+
+// maxDiffCells limits the size of the table used to align list elements.
+// Longer lists are compared element by element.
+const maxDiffCells = 1 << 20
+
+type differ struct {
+	changes []Change
+
+	// patch makes the indices of deleted elements refer to the list with
+	// the changes before them applied, as in DiffPatch.
+	patch bool
+}
+
+func (d *differ) add(kind ChangeKind, path string, old, new *Tag) {
+	d.changes = append(d.changes, Change{kind, path, old, new})
+}
+
+func (d *differ) payload(path string, a, b *Tag) {
+	if a.Type != b.Type {
+		d.add(ChangeType, path, a, b)
+		return
+	}
+
+	switch a.Type {
+	case TypeCompound:
+		if _, ok := compoundLen(a.Payload); !ok {
+			break
+		}
+		if _, ok := compoundLen(b.Payload); !ok {
+			break
+		}
+		d.compound(path, a.Payload, b.Payload)
+		return
+	case TypeList:
+		la, ok1 := a.Payload.(*List)
+		lb, ok2 := b.Payload.(*List)
+		if !ok1 || !ok2 || la == nil || lb == nil || !validList(la) || !validList(lb) {
+			break
+		}
+		if la.Length() == 0 || lb.Length() == 0 || la.Type == lb.Type {
+			d.list(path, la, lb)
+			return
+		}
+	}
+
+	if !a.Equal(b) {
+		d.add(ChangeValue, path, a, b)
+	}
+}
+
+func (d *differ) compound(path string, a, b interface{}) {
+	var names []string
+	eachTag(a, func(name string, tag *Tag) bool {
+		names = append(names, name)
+		return true
+	})
+	eachTag(b, func(name string, tag *Tag) bool {
+		if getTag(a, name) == nil {
+			names = append(names, name)
+		}
+		return true
+	})
+	sort.Strings(names)
+
+	for _, name := range names {
+		ta, tb := getTag(a, name), getTag(b, name)
+		p := childPath(path, name)
+
+		switch {
+		case tb == nil:
+			d.add(ChangeRemoved, p, ta, nil)
+		case ta == nil:
+			d.add(ChangeAdded, p, nil, tb)
+		default:
+			d.payload(p, ta, tb)
+		}
+	}
+}
+
+// list aligns the elements of a and b, reporting unmatched elements as
+// deleted or inserted. Between matches, elements that are similar are
+// compared instead.
+func (d *differ) list(path string, a, b *List) {
+	n, m := a.Length(), b.Length()
+	elemA := func(i int) *Tag { return &Tag{a.Type, listElem(a, i)} }
+	elemB := func(j int) *Tag { return &Tag{b.Type, listElem(b, j)} }
+	equal := func(i, j int) bool { return elemA(i).Equal(elemB(j)) }
+	similar := func(i, j int) bool { return similarTags(elemA(i), elemB(j)) }
+
+	// common prefix and suffix
+	start := 0
+	for start < n && start < m && equal(start, start) {
+		start++
+	}
+	end := 0
+	for end < n-start && end < m-start && equal(n-1-end, m-1-end) {
+		end++
+	}
+
+	pairs := alignRange(start, n-end, start, m-end, equal)
+	i, j := start, start
+	for _, pair := range pairs {
+		similarPairs := alignRange(i, pair[0], j, pair[1], similar)
+		for _, sp := range similarPairs {
+			for ; i < sp[0]; i++ {
+				k := i
+				if d.patch {
+					k = j
+				}
+				d.add(ChangeDeleted, path+indexSegment(k), elemA(i), nil)
+			}
+			for ; j < sp[1]; j++ {
+				d.add(ChangeInserted, path+indexSegment(j), nil, elemB(j))
+			}
+			if i < pair[0] {
+				d.payload(path+indexSegment(j), elemA(i), elemB(j))
+			}
+			i, j = i+1, j+1
+		}
+		i, j = pair[0]+1, pair[1]+1
+	}
+}
+
+// alignRange returns pairs of indices in [i, n) and [j, m) that match by
+// equal, aligned by lcsPairs if the ranges are small enough and otherwise by
+// position, followed by the pair (n, m).
+func alignRange(i, n, j, m int, equal func(i, j int) bool) [][2]int {
+	var pairs [][2]int
+	if rn, rm := n-i, m-j; rn > 0 && rm > 0 && rn*rm <= maxDiffCells {
+		pairs = lcsPairs(rn, rm, func(k, l int) bool { return equal(i+k, j+l) })
+		for k := range pairs {
+			pairs[k][0] += i
+			pairs[k][1] += j
+		}
+	} else {
+		for ; i < n && j < m; i, j = i+1, j+1 {
+			if equal(i, j) {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	return append(pairs, [2]int{n, m})
+}
+
+// similarTags reports whether two list elements are alike enough to be
+// compared rather than reported as deleted and inserted: compounds with at
+// least half their tags equal, and any other elements of the same type.
+func similarTags(a, b *Tag) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	n, ok1 := compoundLen(a.Payload)
+	m, ok2 := compoundLen(b.Payload)
+	if !ok1 || !ok2 {
+		return true
+	}
+	if m > n {
+		n = m
+	}
+
+	same := 0
+	eachTag(a.Payload, func(name string, tag *Tag) bool {
+		if tag.Equal(getTag(b.Payload, name)) {
+			same++
+		}
+		return true
+	})
+	return 2*same >= n
+}
+
+// lcsPairs returns the pairs of indices of a longest common subsequence of
+// sequences of length n and m.
+func lcsPairs(n, m int, equal func(i, j int) bool) [][2]int {
+	// lengths[i*(m+1)+j] is the length for the suffixes from i and j
+	lengths := make([]int, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			k := i*(m+1) + j
+			switch {
+			case equal(i, j):
+				lengths[k] = lengths[k+m+2] + 1
+			case lengths[k+m+1] >= lengths[k+1]:
+				lengths[k] = lengths[k+m+1]
+			default:
+				lengths[k] = lengths[k+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < n && j < m; {
+		k := i*(m+1) + j
+		switch {
+		case lengths[k] == lengths[k+m+2]+1 && equal(i, j):
+			pairs = append(pairs, [2]int{i, j})
+			i, j = i+1, j+1
+		case lengths[k+m+1] >= lengths[k+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// childPath returns the path of the tag of a compound at path.
+func childPath(path, name string) string {
+	if path == "" {
+		return pathName(name)
+	}
+	return path + "." + pathName(name)
+}
@@ -0,0 +1,89 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	a := testPlayer()
+	b := testPlayer()
+
+	player := b.Payload.(Compound)["Data"].Payload.(Compound)["Player"].Payload.(Compound)
+	player["Pos"].Payload.(*List).Array.([]float64)[1] = 65
+	player["minecraft:id"] = &Tag{TypeLong, int64(7)}
+	player["UUID"] = &Tag{TypeIntArray, []int32{4, 3, 2, 1}}
+	player["new tag"] = &Tag{TypeByte, int8(1)}
+	delete(player, "a.b")
+
+	inventory := player["Inventory"].Payload.(*List)
+	items := inventory.Array.([]Compound)
+	items[2]["Count"] = &Tag{TypeByte, int8(2)}
+	inventory.Array = []Compound{
+		{"Slot": &Tag{TypeByte, int8(5)}},
+		items[0],
+		items[2],
+		{"Slot": &Tag{TypeByte, int8(9)}},
+	}
+
+	changes := Diff(a, b)
+
+	dirt := a.Payload.(Compound)["Data"].Payload.(Compound)["Player"].Payload.(Compound)["Inventory"].Payload.(*List).Array.([]Compound)[1]
+	expected := []Change{
+		{ChangeInserted, "Data.Player.Inventory[0]", nil, &Tag{TypeCompound, Compound{"Slot": &Tag{TypeByte, int8(5)}}}},
+		{ChangeDeleted, "Data.Player.Inventory[1]", &Tag{TypeCompound, dirt}, nil},
+		{ChangeValue, "Data.Player.Inventory[2].Count", &Tag{TypeByte, int8(1)}, &Tag{TypeByte, int8(2)}},
+		{ChangeInserted, "Data.Player.Inventory[3]", nil, &Tag{TypeCompound, Compound{"Slot": &Tag{TypeByte, int8(9)}}}},
+		{ChangeValue, "Data.Player.Pos[1]", &Tag{TypeDouble, float64(64)}, &Tag{TypeDouble, float64(65)}},
+		{ChangeValue, "Data.Player.UUID", &Tag{TypeIntArray, []int32{1, 2, 3, 4}}, &Tag{TypeIntArray, []int32{4, 3, 2, 1}}},
+		{ChangeRemoved, `Data.Player."a.b"`, &Tag{TypeString, "dotted"}, nil},
+		{ChangeType, "Data.Player.minecraft:id", &Tag{TypeInt, int32(7)}, &Tag{TypeLong, int64(7)}},
+		{ChangeAdded, `Data.Player."new tag"`, nil, &Tag{TypeByte, int8(1)}},
+	}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	for _, c := range changes {
+		if _, err := ParsePath(c.Path); err != nil {
+			t.Errorf("%s: %v", c.Path, err)
+		}
+	}
+
+	if changes := Diff(a, a.Clone()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	changes := []Change{
+		{ChangeAdded, "a", nil, &Tag{TypeByte, int8(1)}},
+		{ChangeDeleted, "b[0]", &Tag{TypeString, "x"}, nil},
+		{ChangeType, "c", &Tag{TypeInt, int32(7)}, &Tag{TypeLong, int64(7)}},
+		{ChangeValue, "", &Tag{TypeCompound, Compound{}}, &Tag{TypeCompound, Compound{"d": &Tag{TypeDouble, 0.5}}}},
+	}
+
+	expected := `+ a: 1b
+- b[0]: "x"
+! c: 7 (Int) -> 7L (Long)
+~ (root): {} -> {d:0.5d}
+`
+	if s := FormatDiff(changes); s != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestLCSPairs(t *testing.T) {
+	a, b := "ABCBDAB", "BDCABA"
+	pairs := lcsPairs(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+
+	if len(pairs) != 4 {
+		t.Fatalf("expected 4 pairs, got %v", pairs)
+	}
+	for k, p := range pairs {
+		if a[p[0]] != b[p[1]] || k > 0 && (p[0] <= pairs[k-1][0] || p[1] <= pairs[k-1][1]) {
+			t.Fatalf("invalid pairs %v", pairs)
+		}
+	}
+}
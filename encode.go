@@ -6,23 +6,102 @@ import (
 	"io"
 	"math"
 	"reflect"
-	"runtime"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// encoderBufferSize is the size at which the Encoder flushes its buffer.
+const encoderBufferSize = 8192
+
 type Encoder struct {
 	w             io.Writer
+	buf           []byte
+	order         binary.ByteOrder
+	bigEndian     bool
+	network       bool
+	raw           bool
 	sortCompounds bool
+
+	namelessRoot    bool
+	disallowEndRoot bool
+}
+
+type EncoderOptions struct {
+	// ByteOrder of numeric payloads and length prefixes. Defaults to
+	// binary.BigEndian as used by Java Edition; Bedrock Edition files use
+	// binary.LittleEndian.
+	ByteOrder binary.ByteOrder
+
+	// Network selects the encoding used by the Bedrock Edition network
+	// protocol, as described for DecoderOptions.
+	Network bool
+
+	// RawStrings writes strings as standard UTF-8, as Bedrock Edition does,
+	// instead of Java's modified UTF-8.
+	RawStrings bool
+
+	// SortCompounds has the same effect as calling SortCompounds(true).
+	SortCompounds bool
+
+	// NamelessRoot writes the root tag without a name, as expected by the
+	// Java Edition network protocol since 1.20.2.
+	NamelessRoot bool
+
+	// DisallowEndRoot makes Encode fail on a root tag of type End, which the
+	// network protocols use to mean that no NBT is present.
+	DisallowEndRoot bool
 }
 
 func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{w: w}
+	return NewEncoderOptions(w, EncoderOptions{})
 }
 
+func NewEncoderOptions(w io.Writer, opts EncoderOptions) *Encoder {
+	if opts.ByteOrder == nil {
+		opts.ByteOrder = binary.BigEndian
+	}
+	return &Encoder{
+		w:               w,
+		order:           opts.ByteOrder,
+		bigEndian:       opts.ByteOrder == binary.BigEndian,
+		network:         opts.Network,
+		raw:             opts.RawStrings,
+		sortCompounds:   opts.SortCompounds,
+		namelessRoot:    opts.NamelessRoot,
+		disallowEndRoot: opts.DisallowEndRoot,
+	}
+}
+
+// Encode writes tag. A payload that does not match its type, such as a
+// Go int for a tag of type Int, results in an EncodeError.
 func (enc *Encoder) Encode(tag *NamedTag) error {
-	return enc.writeNamedTag(tag)
+	if tag.Type == TypeEnd && enc.disallowEndRoot {
+		return enc.errorf("root tag is End")
+	}
+
+	var err error
+	if !enc.namelessRoot {
+		err = enc.writeNamedTag(tag)
+	} else if err = enc.writeType(tag.Type); err == nil && tag.Type != TypeEnd {
+		err = enc.writePayload(tag.Type, tag.Payload)
+	}
+
+	if err == nil {
+		err = enc.flush()
+	}
+
+	if err != nil {
+		enc.buf = enc.buf[:0]
+		var path string
+		if !enc.namelessRoot && tag.Name != "" {
+			path = pathName(tag.Name)
+		}
+		return rootError(err, path, tag.Type, tag.Payload)
+	}
+	return nil
 }
 
 func (enc *Encoder) SortCompounds(on bool) {
@@ -37,23 +116,66 @@ func (enc *Encoder) errorf(format string, a ...interface{}) error {
 	return enc.wrap(fmt.Errorf(format, a...))
 }
 
-func writeBE(w io.Writer, v interface{}) error {
-	return binary.Write(w, binary.BigEndian, v)
+// flush writes the buffered output.
+func (enc *Encoder) flush() error {
+	if len(enc.buf) == 0 {
+		return nil
+	}
+
+	_, err := enc.w.Write(enc.buf)
+	enc.buf = enc.buf[:0]
+	return enc.wrap(err)
 }
 
-func (enc *Encoder) writeNamedTag(tag *NamedTag) (err error) {
-	// handle possible panics from reflection and type assertions in writeNamedTag and writeList
-	defer func() {
-		if v := recover(); v != nil {
-			switch e := v.(type) {
-			case *reflect.ValueError, *runtime.TypeAssertionError:
-				err = enc.wrap(e.(error))
-			default:
-				panic(v)
-			}
+// flushFull flushes the buffer once it reaches encoderBufferSize.
+func (enc *Encoder) flushFull() error {
+	if len(enc.buf) < encoderBufferSize {
+		return nil
+	}
+	return enc.flush()
+}
+
+// grow extends the buffer by n bytes and returns them to be filled.
+func (enc *Encoder) grow(n int) []byte {
+	if len(enc.buf)+n > cap(enc.buf) {
+		c := 2*cap(enc.buf) + n
+		if c < 256 {
+			c = 256
 		}
-	}()
+		buf := make([]byte, len(enc.buf), c)
+		copy(buf, enc.buf)
+		enc.buf = buf
+	}
+
+	enc.buf = enc.buf[:len(enc.buf)+n]
+	return enc.buf[len(enc.buf)-n:]
+}
+
+func (enc *Encoder) putUint16(b []byte, u uint16) {
+	if enc.bigEndian {
+		binary.BigEndian.PutUint16(b, u)
+	} else {
+		enc.order.PutUint16(b, u)
+	}
+}
 
+func (enc *Encoder) putUint32(b []byte, u uint32) {
+	if enc.bigEndian {
+		binary.BigEndian.PutUint32(b, u)
+	} else {
+		enc.order.PutUint32(b, u)
+	}
+}
+
+func (enc *Encoder) putUint64(b []byte, u uint64) {
+	if enc.bigEndian {
+		binary.BigEndian.PutUint64(b, u)
+	} else {
+		enc.order.PutUint64(b, u)
+	}
+}
+
+func (enc *Encoder) writeNamedTag(tag *NamedTag) error {
 	if err := enc.writeType(tag.Type); err != nil {
 		return err
 	}
@@ -66,55 +188,375 @@ func (enc *Encoder) writeNamedTag(tag *NamedTag) (err error) {
 		return err
 	}
 
-	switch tag.Type {
-	case TypeByte, TypeShort, TypeInt, TypeLong, TypeFloat, TypeDouble:
-		return enc.wrap(writeBE(enc.w, tag.Payload))
+	return enc.writePayload(tag.Type, tag.Payload)
+}
+
+// EncodeError reports the tag that could not be encoded.
+type EncodeError struct {
+	// Path of the tag from the root, such as
+	// root.Inventory[3].tag.display.Name. Names that are empty or contain
+	// special characters are quoted as in ParsePath.
+	Path   string
+	Type   Type
+	GoType reflect.Type // of the payload
+	Err    error
+}
+
+func (e *EncodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *EncodeError) Format(f fmt.State, c rune) {
+	if f.Flag('+') && e.Path != "" {
+		fmt.Fprintf(f, "%s: %+v", e.Path, e.Err)
+	} else if f.Flag('+') {
+		fmt.Fprintf(f, "%+v", e.Err)
+	} else {
+		fmt.Fprint(f, e.Error())
+	}
+}
+
+func (e *EncodeError) Cause() error {
+	return e.Err
+}
+
+// tagError returns err as an EncodeError for the tag with the given type and
+// payload, unless it already is one for a tag inside it, and prefixes its path
+// with segment.
+func tagError(err error, segment string, typ Type, payload interface{}) error {
+	e, ok := err.(*EncodeError)
+	if !ok {
+		e = &EncodeError{Type: typ, GoType: reflect.TypeOf(payload), Err: err}
+	}
+	e.Path = segment + e.Path
+	return e
+}
+
+// rootError is like tagError for a tag at the given path from the root. The
+// leading dot of the path of a nameless root is removed.
+func rootError(err error, path string, typ Type, payload interface{}) error {
+	e := tagError(err, path, typ, payload).(*EncodeError)
+	e.Path = strings.TrimPrefix(e.Path, ".")
+	return e
+}
+
+// pathName quotes name for use in a path if necessary.
+func pathName(name string) string {
+	for i := 0; i < len(name); i++ {
+		if !isPathNameChar(name[i]) {
+			return quoteSNBT(name)
+		}
+	}
+	if name == "" {
+		return quoteSNBT(name)
+	}
+	return name
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// validPayload reports whether payload has the Go type used for typ.
+func validPayload(typ Type, payload interface{}) bool {
+	if typ < TypeByte || typ > TypeLongArray {
+		return false
+	}
+
+	switch p := payload.(type) {
+	case *List:
+		return p != nil && validList(p)
+	case *OrderedCompound:
+		return p != nil && typ == TypeCompound
+	case RawTag:
+		return p.Type() == typ
+	}
+	return reflect.TypeOf(payload) == payloadTypes[typ]
+}
+
+func validList(l *List) bool {
+	if l.Type == TypeEnd {
+		if l.Array == nil {
+			return true
+		}
+		v := reflect.ValueOf(l.Array)
+		return v.Kind() == reflect.Slice && v.Len() == 0
+	}
+
+	if l.Type > TypeLongArray {
+		return false
+	}
+
+	t := reflect.TypeOf(l.Array)
+	if l.Type == TypeCompound && t == reflect.SliceOf(reflect.PtrTo(orderedCompoundType)) {
+		return true
+	}
+	return t == reflect.SliceOf(payloadTypes[l.Type])
+}
+
+func (enc *Encoder) writePayload(typ Type, payload interface{}) error {
+	if typ < TypeByte || typ > TypeLongArray {
+		return enc.errorf("unknown type (%v)", typ)
+	}
+
+	if err := enc.checkPayload(typ, payload); err != nil {
+		return err
+	}
+
+	if raw, ok := payload.(RawTag); ok {
+		return enc.writeBytes(raw[1:])
+	}
+
+	switch typ {
+	case TypeByte:
+		enc.grow(1)[0] = byte(payload.(int8))
+		return nil
+	case TypeShort:
+		enc.putUint16(enc.grow(2), uint16(payload.(int16)))
+		return nil
+	case TypeFloat:
+		enc.putUint32(enc.grow(4), math.Float32bits(payload.(float32)))
+		return nil
+	case TypeDouble:
+		enc.putUint64(enc.grow(8), math.Float64bits(payload.(float64)))
+		return nil
+	case TypeInt:
+		return enc.writeInt32(payload.(int32))
+	case TypeLong:
+		return enc.writeInt64(payload.(int64))
 	case TypeByteArray:
-		return enc.writeByteArray(tag.Payload.([]byte))
+		return enc.writeByteArray(payload.([]byte))
 	case TypeString:
-		return enc.writeString(tag.Payload.(string))
+		return enc.writeString(payload.(string))
 	case TypeList:
-		return enc.writeList(tag.Payload.(*List))
+		return enc.writeList(payload.(*List))
 	case TypeCompound:
-		return enc.writeCompound(tag.Payload.(Compound))
+		if c, ok := payload.(*OrderedCompound); ok {
+			return enc.writeOrderedCompound(c)
+		}
+		return enc.writeCompound(payload.(Compound))
 	case TypeIntArray:
-		return enc.writeIntArray(tag.Payload.([]int32))
-	case TypeLongArray:
-		return enc.writeLongArray(tag.Payload.([]int64))
+		return enc.writeIntArray(payload.([]int32))
 	default:
-		return enc.errorf("unknown type (%v)", tag.Type)
+		return enc.writeLongArray(payload.([]int64))
+	}
+}
+
+// checkPayload returns an error unless payload has the Go type used for typ.
+func (enc *Encoder) checkPayload(typ Type, payload interface{}) error {
+	if validPayload(typ, payload) {
+		return nil
+	}
+	if l, ok := payload.(*List); ok && l != nil {
+		return enc.errorf("invalid list array for type %v (%T)", l.Type, l.Array)
 	}
+	if raw, ok := payload.(RawTag); ok {
+		return enc.errorf("raw tag type mismatch (%v, %v)", typ, raw.Type())
+	}
+	return enc.errorf("invalid payload for type %v (%T)", typ, payload)
 }
 
 func (enc *Encoder) writeType(typ Type) error {
-	return enc.wrap(writeBE(enc.w, typ))
+	if err := enc.flushFull(); err != nil {
+		return err
+	}
+	enc.buf = append(enc.buf, byte(typ))
+	return nil
 }
 
 func (enc *Encoder) writeByteArray(b []byte) error {
 	if err := enc.writeLength(len(b)); err != nil {
 		return err
 	}
-	return enc.wrap(writeBE(enc.w, b))
+	return enc.writeBytes(b)
+}
+
+func (enc *Encoder) writeBytes(b []byte) error {
+	if len(b) < encoderBufferSize {
+		enc.buf = append(enc.buf, b...)
+		return nil
+	}
+
+	// large arrays bypass the buffer
+	if err := enc.flush(); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(b)
+	return enc.wrap(err)
+}
+
+func (enc *Encoder) writeInt32(n int32) error {
+	if !enc.network {
+		enc.putUint32(enc.grow(4), uint32(n))
+		return nil
+	}
+	return enc.writeUvarint(uint64(uint32(n<<1 ^ n>>31)))
+}
+
+func (enc *Encoder) writeInt64(n int64) error {
+	if !enc.network {
+		enc.putUint64(enc.grow(8), uint64(n))
+		return nil
+	}
+	return enc.writeUvarint(uint64(n<<1 ^ n>>63))
+}
+
+func (enc *Encoder) writeUvarint(u uint64) error {
+	b := enc.grow(binary.MaxVarintLen64)
+	n := binary.PutUvarint(b, u)
+	enc.buf = enc.buf[:len(enc.buf)-len(b)+n]
+	return nil
+}
+
+// writeNumbers writes a slice of numeric payloads.
+func (enc *Encoder) writeNumbers(a interface{}) error {
+	if enc.network {
+		switch a := a.(type) {
+		case []int32:
+			for _, n := range a {
+				if err := enc.writeInt32(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		case []int64:
+			for _, n := range a {
+				if err := enc.writeInt64(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	switch a := a.(type) {
+	case []byte:
+		return enc.writeElems(len(a), 1, func(b []byte, i int) {
+			copy(b, a[i:])
+		})
+	case []int8:
+		return enc.writeElems(len(a), 1, func(b []byte, i int) {
+			for j := range b {
+				b[j] = byte(a[i])
+				i++
+			}
+		})
+	case []int16:
+		return enc.writeElems(len(a), 2, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 2 {
+				enc.putUint16(b[j:], uint16(a[i]))
+				i++
+			}
+		})
+	case []int32:
+		return enc.writeElems(len(a), 4, func(b []byte, i int) {
+			if enc.bigEndian {
+				for j := 0; j < len(b); j += 4 {
+					binary.BigEndian.PutUint32(b[j:], uint32(a[i]))
+					i++
+				}
+				return
+			}
+			for j := 0; j < len(b); j += 4 {
+				enc.order.PutUint32(b[j:], uint32(a[i]))
+				i++
+			}
+		})
+	case []int64:
+		return enc.writeElems(len(a), 8, func(b []byte, i int) {
+			if enc.bigEndian {
+				for j := 0; j < len(b); j += 8 {
+					binary.BigEndian.PutUint64(b[j:], uint64(a[i]))
+					i++
+				}
+				return
+			}
+			for j := 0; j < len(b); j += 8 {
+				enc.order.PutUint64(b[j:], uint64(a[i]))
+				i++
+			}
+		})
+	case []float32:
+		return enc.writeElems(len(a), 4, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 4 {
+				enc.putUint32(b[j:], math.Float32bits(a[i]))
+				i++
+			}
+		})
+	case []float64:
+		return enc.writeElems(len(a), 8, func(b []byte, i int) {
+			for j := 0; j < len(b); j += 8 {
+				enc.putUint64(b[j:], math.Float64bits(a[i]))
+				i++
+			}
+		})
+	default:
+		return enc.errorf("unsupported numbers (%T)", a)
+	}
+}
+
+// writeElems writes n elements of the given size in pieces that fit in the
+// buffer, calling f to fill each from the element at index i.
+func (enc *Encoder) writeElems(n, size int, f func(b []byte, i int)) error {
+	for i := 0; i < n; {
+		k := n - i
+		if max := encoderBufferSize / size; k > max {
+			k = max
+		}
+
+		f(enc.grow(k*size), i)
+		i += k
+
+		if err := enc.flushFull(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (enc *Encoder) writeLength(length int) error {
 	if length > math.MaxInt32 {
 		return enc.errorf("length overflows int32 (%d)", length)
 	}
-	return enc.wrap(writeBE(enc.w, int32(length)))
+	return enc.writeInt32(int32(length))
 }
 
 func (enc *Encoder) writeString(s string) error {
+	if err := enc.flushFull(); err != nil {
+		return err
+	}
+
 	length := len(s)
-	if length > math.MaxInt16 {
-		return enc.errorf("length overflows int16 (%d)", length)
+	if !enc.raw {
+		length = mutf8Len(s)
 	}
 
-	if err := writeBE(enc.w, int16(length)); err != nil {
-		return enc.wrap(err)
+	if enc.network {
+		if length > math.MaxInt32 {
+			return enc.errorf("length overflows int32 (%d)", length)
+		}
+
+		if err := enc.writeUvarint(uint64(length)); err != nil {
+			return err
+		}
+	} else {
+		if length > math.MaxUint16 {
+			return enc.errorf("length overflows uint16 (%d)", length)
+		}
+
+		enc.putUint16(enc.grow(2), uint16(length))
 	}
 
-	return enc.wrap(writeBE(enc.w, []byte(s)))
+	// modified UTF-8 only differs from s where it is longer
+	if enc.raw || length == len(s) {
+		enc.buf = append(enc.buf, s...)
+	} else {
+		enc.buf = appendMUTF8(enc.buf, s)
+	}
+	return nil
 }
 
 func (enc *Encoder) writeList(l *List) error {
@@ -129,41 +571,50 @@ func (enc *Encoder) writeList(l *List) error {
 	switch l.Type {
 	case TypeEnd:
 	case TypeByte, TypeShort, TypeInt, TypeLong, TypeFloat, TypeDouble:
-		return enc.wrap(writeBE(enc.w, l.Array))
+		return enc.writeNumbers(l.Array)
 	case TypeByteArray:
-		for _, a := range l.Array.([][]byte) {
+		for i, a := range l.Array.([][]byte) {
 			if err := enc.writeByteArray(a); err != nil {
-				return err
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	case TypeString:
-		for _, a := range l.Array.([]string) {
+		for i, a := range l.Array.([]string) {
 			if err := enc.writeString(a); err != nil {
-				return err
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	case TypeList:
-		for _, a := range l.Array.([]*List) {
-			if err := enc.writeList(a); err != nil {
-				return err
+		for i, a := range l.Array.([]*List) {
+			if err := enc.writePayload(TypeList, a); err != nil {
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	case TypeCompound:
-		for _, a := range l.Array.([]Compound) {
+		if array, ok := l.Array.([]*OrderedCompound); ok {
+			for i, a := range array {
+				if err := enc.writePayload(TypeCompound, a); err != nil {
+					return tagError(err, indexSegment(i), l.Type, a)
+				}
+			}
+			break
+		}
+
+		for i, a := range l.Array.([]Compound) {
 			if err := enc.writeCompound(a); err != nil {
-				return err
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	case TypeIntArray:
-		for _, a := range l.Array.([][]int32) {
+		for i, a := range l.Array.([][]int32) {
 			if err := enc.writeIntArray(a); err != nil {
-				return err
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	case TypeLongArray:
-		for _, a := range l.Array.([][]int64) {
+		for i, a := range l.Array.([][]int64) {
 			if err := enc.writeLongArray(a); err != nil {
-				return err
+				return tagError(err, indexSegment(i), l.Type, a)
 			}
 		}
 	default:
@@ -175,21 +626,19 @@ func (enc *Encoder) writeList(l *List) error {
 
 func (enc *Encoder) writeCompound(m Compound) error {
 	if enc.sortCompounds {
-		a := make([]*NamedTag, len(m))
-		var i int
-		for name, tag := range m {
-			a[i] = &NamedTag{tag.Type, name, tag.Payload}
-			i++
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
 		}
-		sort.Slice(a, func(i, j int) bool { return a[i].Name < a[j].Name })
-		for _, tag := range a {
-			if err := enc.writeNamedTag(tag); err != nil {
+		sort.Strings(names)
+		for _, name := range names {
+			if err := enc.writeChild(name, m[name]); err != nil {
 				return err
 			}
 		}
 	} else {
 		for name, tag := range m {
-			if err := enc.writeNamedTag(&NamedTag{tag.Type, name, tag.Payload}); err != nil {
+			if err := enc.writeChild(name, tag); err != nil {
 				return err
 			}
 		}
@@ -197,16 +646,335 @@ func (enc *Encoder) writeCompound(m Compound) error {
 	return enc.writeNamedTag(&NamedTag{})
 }
 
+// writeOrderedCompound writes the tags of c in order, unless compounds are
+// to be sorted.
+func (enc *Encoder) writeOrderedCompound(c *OrderedCompound) error {
+	names := c.Names()
+	if enc.sortCompounds {
+		names = append([]string(nil), names...)
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		if err := enc.writeChild(name, c.Get(name)); err != nil {
+			return err
+		}
+	}
+	return enc.writeNamedTag(&NamedTag{})
+}
+
+// writeChild writes a tag of a compound.
+func (enc *Encoder) writeChild(name string, tag *Tag) error {
+	if tag == nil {
+		return tagError(enc.errorf("nil tag"), "."+pathName(name), TypeEnd, nil)
+	}
+
+	if tag.Type == TypeEnd {
+		return tagError(enc.errorf("End tag in compound"), "."+pathName(name), TypeEnd, tag.Payload)
+	}
+
+	if err := enc.writeNamedTag(&NamedTag{tag.Type, name, tag.Payload}); err != nil {
+		return tagError(err, "."+pathName(name), tag.Type, tag.Payload)
+	}
+	return nil
+}
+
 func (enc *Encoder) writeIntArray(a []int32) error {
 	if err := enc.writeLength(len(a)); err != nil {
 		return err
 	}
-	return enc.wrap(writeBE(enc.w, a))
+	return enc.writeNumbers(a)
 }
 
 func (enc *Encoder) writeLongArray(a []int64) error {
 	if err := enc.writeLength(len(a)); err != nil {
 		return err
 	}
-	return enc.wrap(writeBE(enc.w, a))
+	return enc.writeNumbers(a)
+}
+
+// EncodedSize returns the number of bytes Encode writes for tag with the
+// default options, without encoding it.
+func EncodedSize(tag *NamedTag) (int64, error) {
+	return EncodedSizeOptions(tag, EncoderOptions{})
+}
+
+// EncodedSizeOptions is like EncodedSize for an Encoder with the given
+// options. Byte order does not affect the size.
+func EncodedSizeOptions(tag *NamedTag, opts EncoderOptions) (int64, error) {
+	return NewEncoderOptions(nil, opts).size(tag)
+}
+
+func (enc *Encoder) size(tag *NamedTag) (int64, error) {
+	if tag.Type == TypeEnd && enc.disallowEndRoot {
+		return 0, enc.errorf("root tag is End")
+	}
+
+	n, err := int64(1), error(nil)
+	if tag.Type != TypeEnd {
+		var m int64
+		if !enc.namelessRoot {
+			m, err = enc.stringSize(tag.Name)
+			n += m
+		}
+		if err == nil {
+			m, err = enc.payloadSize(tag.Type, tag.Payload)
+			n += m
+		}
+	}
+
+	if err != nil {
+		var path string
+		if !enc.namelessRoot && tag.Name != "" {
+			path = pathName(tag.Name)
+		}
+		return 0, rootError(err, path, tag.Type, tag.Payload)
+	}
+	return n, nil
+}
+
+// payloadSize mirrors writePayload.
+func (enc *Encoder) payloadSize(typ Type, payload interface{}) (int64, error) {
+	if typ < TypeByte || typ > TypeLongArray {
+		return 0, enc.errorf("unknown type (%v)", typ)
+	}
+
+	if err := enc.checkPayload(typ, payload); err != nil {
+		return 0, err
+	}
+
+	if raw, ok := payload.(RawTag); ok {
+		return int64(len(raw) - 1), nil
+	}
+
+	switch typ {
+	case TypeByte, TypeShort, TypeFloat, TypeDouble:
+		return payloadSizes[typ], nil
+	case TypeInt:
+		return enc.int32Size(payload.(int32)), nil
+	case TypeLong:
+		return enc.int64Size(payload.(int64)), nil
+	case TypeByteArray:
+		a := payload.([]byte)
+		n, err := enc.lengthSize(len(a))
+		return n + int64(len(a)), err
+	case TypeString:
+		return enc.stringSize(payload.(string))
+	case TypeList:
+		return enc.listSize(payload.(*List))
+	case TypeCompound:
+		if c, ok := payload.(*OrderedCompound); ok {
+			return enc.orderedCompoundSize(c)
+		}
+		return enc.compoundSize(payload.(Compound))
+	case TypeIntArray:
+		return enc.numbersSize(payload.([]int32))
+	default:
+		return enc.numbersSize(payload.([]int64))
+	}
+}
+
+func (enc *Encoder) int32Size(n int32) int64 {
+	if !enc.network {
+		return 4
+	}
+	return uvarintSize(uint64(uint32(n<<1 ^ n>>31)))
+}
+
+func (enc *Encoder) int64Size(n int64) int64 {
+	if !enc.network {
+		return 8
+	}
+	return uvarintSize(uint64(n<<1 ^ n>>63))
+}
+
+func uvarintSize(u uint64) int64 {
+	n := int64(1)
+	for ; u >= 0x80; u >>= 7 {
+		n++
+	}
+	return n
+}
+
+// lengthSize is the size of a length written by writeLength.
+func (enc *Encoder) lengthSize(length int) (int64, error) {
+	if length > math.MaxInt32 {
+		return 0, enc.errorf("length overflows int32 (%d)", length)
+	}
+	return enc.int32Size(int32(length)), nil
+}
+
+// numbersSize is the size of a numeric array or list with its length.
+func (enc *Encoder) numbersSize(a interface{}) (int64, error) {
+	var (
+		length int
+		size   int64
+	)
+	switch a := a.(type) {
+	case []int8:
+		length, size = len(a), int64(len(a))
+	case []int16:
+		length, size = len(a), 2*int64(len(a))
+	case []int32:
+		length, size = len(a), 4*int64(len(a))
+		if enc.network {
+			size = 0
+			for _, n := range a {
+				size += enc.int32Size(n)
+			}
+		}
+	case []int64:
+		length, size = len(a), 8*int64(len(a))
+		if enc.network {
+			size = 0
+			for _, n := range a {
+				size += enc.int64Size(n)
+			}
+		}
+	case []float32:
+		length, size = len(a), 4*int64(len(a))
+	case []float64:
+		length, size = len(a), 8*int64(len(a))
+	}
+
+	n, err := enc.lengthSize(length)
+	return n + size, err
+}
+
+func (enc *Encoder) stringSize(s string) (int64, error) {
+	length := len(s)
+	if !enc.raw {
+		length = mutf8Len(s)
+	}
+
+	if enc.network {
+		if length > math.MaxInt32 {
+			return 0, enc.errorf("length overflows int32 (%d)", length)
+		}
+		return uvarintSize(uint64(length)) + int64(length), nil
+	}
+
+	if length > math.MaxUint16 {
+		return 0, enc.errorf("length overflows uint16 (%d)", length)
+	}
+	return 2 + int64(length), nil
+}
+
+// listSize mirrors writeList.
+func (enc *Encoder) listSize(l *List) (int64, error) {
+	switch l.Type {
+	case TypeEnd:
+		n, err := enc.lengthSize(0)
+		return 1 + n, err
+	case TypeByte, TypeShort, TypeInt, TypeLong, TypeFloat, TypeDouble:
+		n, err := enc.numbersSize(l.Array)
+		return 1 + n, err
+	}
+
+	n, err := enc.lengthSize(l.Length())
+	if err != nil {
+		return 0, err
+	}
+	n++
+
+	elem := func(i int, a interface{}) error {
+		m, err := enc.payloadSize(l.Type, a)
+		if err != nil {
+			return tagError(err, indexSegment(i), l.Type, a)
+		}
+		n += m
+		return nil
+	}
+
+	switch array := l.Array.(type) {
+	case [][]byte:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case []string:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case []*List:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case []*OrderedCompound:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case []Compound:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case [][]int32:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	case [][]int64:
+		for i, a := range array {
+			if err = elem(i, a); err != nil {
+				break
+			}
+		}
+	}
+	return n, err
+}
+
+func (enc *Encoder) compoundSize(m Compound) (int64, error) {
+	n := int64(1)
+	for name, tag := range m {
+		size, err := enc.childSize(name, tag)
+		if err != nil {
+			return 0, err
+		}
+		n += size
+	}
+	return n, nil
+}
+
+func (enc *Encoder) orderedCompoundSize(c *OrderedCompound) (int64, error) {
+	n := int64(1)
+	for _, name := range c.Names() {
+		size, err := enc.childSize(name, c.Get(name))
+		if err != nil {
+			return 0, err
+		}
+		n += size
+	}
+	return n, nil
+}
+
+// childSize mirrors writeChild.
+func (enc *Encoder) childSize(name string, tag *Tag) (int64, error) {
+	if tag == nil {
+		return 0, tagError(enc.errorf("nil tag"), "."+pathName(name), TypeEnd, nil)
+	}
+
+	if tag.Type == TypeEnd {
+		return 0, tagError(enc.errorf("End tag in compound"), "."+pathName(name), TypeEnd, tag.Payload)
+	}
+
+	n, err := enc.stringSize(name)
+	if err == nil {
+		var m int64
+		m, err = enc.payloadSize(tag.Type, tag.Payload)
+		n += 1 + m
+	}
+	if err != nil {
+		return 0, tagError(err, "."+pathName(name), tag.Type, tag.Payload)
+	}
+	return n, nil
 }
@@ -2,6 +2,11 @@ package nbt
 
 import (
 	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -21,3 +26,179 @@ func TestEncoder(t *testing.T) {
 		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
 	}
 }
+
+func TestEncoderLittleEndian(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderOptions(buf, EncoderOptions{ByteOrder: binary.LittleEndian, SortCompounds: true})
+
+	if err := enc.Encode(testTag); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if diff := cmp.Diff(testDataLE, data); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestEncoderNetwork(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderOptions(buf, EncoderOptions{ByteOrder: binary.LittleEndian, Network: true, SortCompounds: true})
+
+	if err := enc.Encode(testTag); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if diff := cmp.Diff(testDataNetwork, data); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestEncoderNamelessRoot(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderOptions(buf, EncoderOptions{SortCompounds: true, NamelessRoot: true})
+
+	if err := enc.Encode(testTag); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := append([]byte{testData[0]}, testData[7:]...)
+	if diff := cmp.Diff(expected, buf.Bytes()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestEncoderStringLength(t *testing.T) {
+	// each NUL takes two bytes in modified UTF-8
+	s := strings.Repeat("\x00", math.MaxUint16/2+1)
+
+	if err := NewEncoder(ioutil.Discard).Encode(&NamedTag{TypeString, "", s}); err == nil {
+		t.Fatal("expected error for string longer than 65535 bytes")
+	}
+
+	enc := NewEncoderOptions(ioutil.Discard, EncoderOptions{RawStrings: true})
+	if err := enc.Encode(&NamedTag{TypeString, "", s}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	inventory := &List{TypeCompound, []Compound{{}, {}, {}, {
+		"tag": &Tag{TypeCompound, Compound{
+			"display": &Tag{TypeCompound, Compound{
+				"Name": &Tag{TypeString, 42},
+			}},
+		}},
+	}}}
+
+	tests := []struct {
+		tag    *NamedTag
+		path   string
+		typ    Type
+		goType reflect.Type
+	}{
+		{
+			&NamedTag{TypeCompound, "root", Compound{"Inventory": &Tag{TypeList, inventory}}},
+			"root.Inventory[3].tag.display.Name", TypeString, reflect.TypeOf(0),
+		},
+		{
+			&NamedTag{TypeCompound, "", Compound{"a b": &Tag{TypeList, (*List)(nil)}}},
+			`"a b"`, TypeList, reflect.TypeOf((*List)(nil)),
+		},
+		{
+			&NamedTag{TypeList, "list", &List{TypeList, []*List{{TypeInt, []int{1}}}}},
+			"list[0]", TypeList, reflect.TypeOf((*List)(nil)),
+		},
+		{
+			&NamedTag{TypeInt, "", int64(1)},
+			"", TypeInt, reflect.TypeOf(int64(0)),
+		},
+		{
+			&NamedTag{TypeCompound, "", Compound{"nil": nil}},
+			"nil", TypeEnd, nil,
+		},
+		{
+			&NamedTag{TypeCompound, "", Compound{`say "hi"`: nil}},
+			`'say "hi"'`, TypeEnd, nil,
+		},
+	}
+
+	for _, test := range tests {
+		err := NewEncoder(ioutil.Discard).Encode(test.tag)
+		e, ok := err.(*EncodeError)
+		if !ok {
+			t.Errorf("%s: expected EncodeError, got %v", test.path, err)
+			continue
+		}
+
+		if e.Path != test.path || e.Type != test.typ || e.GoType != test.goType {
+			t.Errorf("%s: unexpected error %q (%v, %v)", test.path, e.Path, e.Type, e.GoType)
+		}
+
+		// paths from nameless roots are valid for ParsePath
+		if test.tag.Name == "" && e.Path != "" {
+			if _, err := ParsePath(e.Path); err != nil {
+				t.Errorf("%s: %v", test.path, err)
+			}
+		}
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	tags := []*NamedTag{testTag, benchChunk(), {TypeString, "\x00😀", "é\xff"}, {}}
+	opts := []EncoderOptions{
+		{},
+		{ByteOrder: binary.LittleEndian, Network: true},
+		{NamelessRoot: true},
+		{RawStrings: true},
+	}
+
+	for _, tag := range tags {
+		for _, opt := range opts {
+			buf := new(bytes.Buffer)
+			if err := NewEncoderOptions(buf, opt).Encode(tag); err != nil {
+				t.Fatal(err)
+			}
+
+			size, err := EncodedSizeOptions(tag, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != int64(buf.Len()) {
+				t.Errorf("%q %+v: expected %d, got %d", tag.Name, opt, buf.Len(), size)
+			}
+		}
+	}
+
+	bad := &NamedTag{TypeCompound, "root", Compound{
+		"x": &Tag{TypeCompound, Compound{"y": &Tag{TypeInt, 1}}},
+	}}
+	_, err := EncodedSize(bad)
+	if e, ok := err.(*EncodeError); !ok || e.Path != "root.x.y" {
+		t.Errorf("expected EncodeError at root.x.y, got %v", err)
+	}
+}
+
+func benchmarkEncoder(b *testing.B, tag *NamedTag) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(tag); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := NewEncoder(ioutil.Discard).Encode(tag); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	benchmarkEncoder(b, testTag)
+}
+
+func BenchmarkEncoderChunk(b *testing.B) {
+	benchmarkEncoder(b, benchChunk())
+}
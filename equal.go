@@ -0,0 +1,422 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Equal reports whether tag and other have the same name, type and payload.
+// Compounds are equal if they have equal tags in any order, including an
+// OrderedCompound and a Compound. Floats are compared by their bits, so that
+// NaN equals itself but not -0. Empty lists are equal whatever their element
+// type. A RawTag is decoded with the default options unless it has the same
+// bytes as the other.
+func (tag *NamedTag) Equal(other *NamedTag) bool {
+	if tag == nil || other == nil {
+		return tag == other
+	}
+	return tag.Name == other.Name && tag.Type == other.Type && payloadEqual(tag.Type, tag.Payload, other.Payload)
+}
+
+func (tag *Tag) Equal(other *Tag) bool {
+	if tag == nil || other == nil {
+		return tag == other
+	}
+	return tag.Type == other.Type && payloadEqual(tag.Type, tag.Payload, other.Payload)
+}
+
+func (l *List) Equal(other *List) bool {
+	if l == nil || other == nil {
+		return l == other
+	}
+
+	n := l.Length()
+	if n != other.Length() {
+		return false
+	}
+	if n == 0 {
+		return true
+	}
+	if l.Type != other.Type {
+		return false
+	}
+
+	switch l.Type {
+	case TypeCompound:
+		for i := 0; i < n; i++ {
+			if !compoundEqual(compoundAt(l, i), compoundAt(other, i)) {
+				return false
+			}
+		}
+		return true
+	case TypeFloat:
+		a, ok1 := l.Array.([]float32)
+		b, ok2 := other.Array.([]float32)
+		if !ok1 || !ok2 {
+			return false
+		}
+		for i := range a {
+			if math.Float32bits(a[i]) != math.Float32bits(b[i]) {
+				return false
+			}
+		}
+		return true
+	case TypeDouble:
+		a, ok1 := l.Array.([]float64)
+		b, ok2 := other.Array.([]float64)
+		if !ok1 || !ok2 {
+			return false
+		}
+		for i := range a {
+			if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i := 0; i < n; i++ {
+		if !payloadEqual(l.Type, listElem(l, i), listElem(other, i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Compound) Equal(other Compound) bool {
+	return compoundEqual(m, other)
+}
+
+func (c *OrderedCompound) Equal(other *OrderedCompound) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return compoundEqual(c, other)
+}
+
+func payloadEqual(typ Type, a, b interface{}) bool {
+	rawA, okA := a.(RawTag)
+	rawB, okB := b.(RawTag)
+	if okA && okB && bytes.Equal(rawA, rawB) {
+		return true
+	}
+	if okA {
+		var err error
+		if a, err = rawA.Decode(); err != nil {
+			return false
+		}
+	}
+	if okB {
+		var err error
+		if b, err = rawB.Decode(); err != nil {
+			return false
+		}
+	}
+
+	switch typ {
+	case TypeEnd:
+		return true
+	case TypeFloat:
+		x, ok1 := a.(float32)
+		y, ok2 := b.(float32)
+		return ok1 && ok2 && math.Float32bits(x) == math.Float32bits(y)
+	case TypeDouble:
+		x, ok1 := a.(float64)
+		y, ok2 := b.(float64)
+		return ok1 && ok2 && math.Float64bits(x) == math.Float64bits(y)
+	case TypeByteArray:
+		x, ok1 := a.([]byte)
+		y, ok2 := b.([]byte)
+		return ok1 && ok2 && bytes.Equal(x, y)
+	case TypeIntArray:
+		x, ok1 := a.([]int32)
+		y, ok2 := b.([]int32)
+		if !ok1 || !ok2 || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i] != y[i] {
+				return false
+			}
+		}
+		return true
+	case TypeLongArray:
+		x, ok1 := a.([]int64)
+		y, ok2 := b.([]int64)
+		if !ok1 || !ok2 || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i] != y[i] {
+				return false
+			}
+		}
+		return true
+	case TypeList:
+		x, ok1 := a.(*List)
+		y, ok2 := b.(*List)
+		return ok1 && ok2 && x.Equal(y)
+	case TypeCompound:
+		return compoundEqual(a, b)
+	case TypeByte, TypeShort, TypeInt, TypeLong, TypeString:
+		return a == b
+	default:
+		return false
+	}
+}
+
+// compoundEqual compares two Compound or *OrderedCompound payloads.
+func compoundEqual(a, b interface{}) bool {
+	n, ok1 := compoundLen(a)
+	m, ok2 := compoundLen(b)
+	if !ok1 || !ok2 || n != m {
+		return false
+	}
+
+	equal := true
+	eachTag(a, func(name string, tag *Tag) bool {
+		equal = tag.Equal(getTag(b, name))
+		return equal
+	})
+	return equal
+}
+
+func compoundLen(payload interface{}) (int, bool) {
+	switch c := payload.(type) {
+	case Compound:
+		return len(c), true
+	case *OrderedCompound:
+		if c != nil {
+			return c.Len(), true
+		}
+	}
+	return 0, false
+}
+
+func getTag(payload interface{}, name string) *Tag {
+	if c, ok := payload.(*OrderedCompound); ok {
+		return c.Get(name)
+	}
+	return payload.(Compound)[name]
+}
+
+// eachTag calls f for the tags of a compound until it returns false.
+func eachTag(payload interface{}, f func(name string, tag *Tag) bool) {
+	if c, ok := payload.(*OrderedCompound); ok {
+		for _, name := range c.Names() {
+			if !f(name, c.Get(name)) {
+				return
+			}
+		}
+		return
+	}
+
+	for name, tag := range payload.(Compound) {
+		if !f(name, tag) {
+			return
+		}
+	}
+}
+
+// compoundAt returns element i of a list of compounds.
+func compoundAt(l *List, i int) interface{} {
+	if a, ok := l.Array.([]*OrderedCompound); ok {
+		return a[i]
+	}
+	if a, ok := l.Array.([]Compound); ok {
+		return a[i]
+	}
+	return nil
+}
+
+// listElem returns element i of a list, or nil if its array is invalid.
+func listElem(l *List, i int) interface{} {
+	switch a := l.Array.(type) {
+	case []int8:
+		return a[i]
+	case []int16:
+		return a[i]
+	case []int32:
+		return a[i]
+	case []int64:
+		return a[i]
+	case []float32:
+		return a[i]
+	case []float64:
+		return a[i]
+	case [][]byte:
+		return a[i]
+	case []string:
+		return a[i]
+	case []*List:
+		return a[i]
+	case []Compound:
+		return a[i]
+	case []*OrderedCompound:
+		return a[i]
+	case [][]int32:
+		return a[i]
+	case [][]int64:
+		return a[i]
+	}
+	return nil
+}
+
+// Hash returns a hash of tag that is equal for equal tags, as defined by
+// Equal, and stable across processes, such as for deduplicating item stacks.
+// It is not suitable for security purposes. All nil tags have the same hash.
+func (tag *NamedTag) Hash() uint64 {
+	h := newTagHasher()
+	if tag == nil {
+		return h.h.Sum64()
+	}
+	h.string(tag.Name)
+	h.payload(tag.Type, tag.Payload)
+	return h.h.Sum64()
+}
+
+func (tag *Tag) Hash() uint64 {
+	h := newTagHasher()
+	if tag == nil {
+		return h.h.Sum64()
+	}
+	h.payload(tag.Type, tag.Payload)
+	return h.h.Sum64()
+}
+
+func (l *List) Hash() uint64 {
+	h := newTagHasher()
+	if l == nil {
+		return h.h.Sum64()
+	}
+	h.payload(TypeList, l)
+	return h.h.Sum64()
+}
+
+func (m Compound) Hash() uint64 {
+	h := newTagHasher()
+	h.payload(TypeCompound, m)
+	return h.h.Sum64()
+}
+
+func (c *OrderedCompound) Hash() uint64 {
+	h := newTagHasher()
+	if c == nil {
+		return h.h.Sum64()
+	}
+	h.payload(TypeCompound, c)
+	return h.h.Sum64()
+}
+
+// tagHasher hashes payloads in a form like their big-endian encoding with
+// sorted compounds.
+type tagHasher struct {
+	h hash.Hash64
+	b [8]byte
+}
+
+func newTagHasher() *tagHasher {
+	return &tagHasher{h: fnv.New64a()}
+}
+
+func (h *tagHasher) uint(u uint64, size int) {
+	binary.BigEndian.PutUint64(h.b[:], u)
+	h.h.Write(h.b[8-size:])
+}
+
+func (h *tagHasher) string(s string) {
+	h.uint(uint64(len(s)), 4)
+	h.h.Write([]byte(s))
+}
+
+func (h *tagHasher) payload(typ Type, payload interface{}) {
+	if raw, ok := payload.(RawTag); ok {
+		if p, err := raw.Decode(); err == nil {
+			payload = p
+		}
+	}
+
+	h.uint(uint64(typ), 1)
+	if typ == TypeEnd {
+		return
+	}
+
+	switch p := payload.(type) {
+	case int8:
+		h.uint(uint64(p), 1)
+	case int16:
+		h.uint(uint64(p), 2)
+	case int32:
+		h.uint(uint64(p), 4)
+	case int64:
+		h.uint(uint64(p), 8)
+	case float32:
+		h.uint(uint64(math.Float32bits(p)), 4)
+	case float64:
+		h.uint(math.Float64bits(p), 8)
+	case string:
+		h.string(p)
+	case []byte:
+		h.uint(uint64(len(p)), 4)
+		h.h.Write(p)
+	case []int32:
+		h.uint(uint64(len(p)), 4)
+		for _, n := range p {
+			h.uint(uint64(n), 4)
+		}
+	case []int64:
+		h.uint(uint64(len(p)), 4)
+		for _, n := range p {
+			h.uint(uint64(n), 8)
+		}
+	case *List:
+		h.list(p)
+	case Compound, *OrderedCompound:
+		h.compound(p)
+	default:
+		// an invalid payload only contributes its Go type
+		h.string(fmt.Sprintf("%T", p))
+	}
+}
+
+func (h *tagHasher) list(l *List) {
+	if l == nil {
+		return
+	}
+
+	n := l.Length()
+	if n == 0 {
+		h.uint(uint64(TypeEnd), 1)
+		h.uint(0, 4)
+		return
+	}
+
+	h.uint(uint64(l.Type), 1)
+	h.uint(uint64(n), 4)
+	for i := 0; i < n; i++ {
+		h.payload(l.Type, listElem(l, i))
+	}
+}
+
+func (h *tagHasher) compound(payload interface{}) {
+	n, _ := compoundLen(payload)
+	names := make([]string, 0, n)
+	eachTag(payload, func(name string, tag *Tag) bool {
+		names = append(names, name)
+		return true
+	})
+	sort.Strings(names)
+
+	for _, name := range names {
+		h.string(name)
+		if tag := getTag(payload, name); tag != nil {
+			h.payload(tag.Type, tag.Payload)
+		}
+	}
+	h.uint(uint64(TypeEnd), 1)
+}
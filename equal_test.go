@@ -0,0 +1,106 @@
+package nbt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	nan := math.Float32frombits(0x7fc00001)
+	ordered := OrderCompound(Compound{
+		"a": &Tag{TypeInt, int32(1)},
+		"b": &Tag{TypeFloat, nan},
+	})
+	reversed := NewOrderedCompound()
+	reversed.Set("b", &Tag{TypeFloat, nan})
+	reversed.Set("a", &Tag{TypeInt, int32(1)})
+	raw, err := NewRawTag(TypeCompound, ordered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawReversed, err := NewRawTag(TypeCompound, reversed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawOther, err := NewRawTag(TypeCompound, Compound{"a": &Tag{TypeInt, int32(2)}, "b": &Tag{TypeFloat, nan}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b  *Tag
+		equal bool
+	}{
+		{testTag.Payload.(Compound)["byteMin"], testTag.Clone().Payload.(Compound)["byteMin"], true},
+		{&Tag{TypeCompound, testTag.Payload}, &Tag{TypeCompound, testTag.Clone().Payload}, true},
+		{&Tag{TypeCompound, ordered}, &Tag{TypeCompound, reversed}, true},
+		{&Tag{TypeCompound, ordered}, &Tag{TypeCompound, ordered.Compound()}, true},
+		{&Tag{TypeCompound, ordered}, &Tag{TypeCompound, raw}, true},
+		{&Tag{TypeCompound, raw}, &Tag{TypeCompound, rawReversed}, true},
+		{&Tag{TypeCompound, raw}, &Tag{TypeCompound, rawOther}, false},
+		{&Tag{TypeDouble, math.NaN()}, &Tag{TypeDouble, math.NaN()}, true},
+		{&Tag{TypeDouble, 0.0}, &Tag{TypeDouble, math.Copysign(0, -1)}, false},
+		{&Tag{TypeFloat, float32(1)}, &Tag{TypeDouble, float64(1)}, false},
+		{&Tag{TypeInt, int32(1)}, &Tag{TypeInt, int64(1)}, false},
+		{&Tag{TypeList, &List{TypeEnd, nil}}, &Tag{TypeList, &List{TypeInt, []int32{}}}, true},
+		{&Tag{TypeList, &List{TypeInt, []int32{1}}}, &Tag{TypeList, &List{TypeLong, []int64{1}}}, false},
+		{&Tag{TypeList, &List{TypeDouble, []float64{math.NaN()}}}, &Tag{TypeList, &List{TypeDouble, []float64{math.NaN()}}}, true},
+		{
+			&Tag{TypeList, &List{TypeCompound, []Compound{{"a": &Tag{TypeInt, int32(1)}, "b": &Tag{TypeFloat, nan}}}}},
+			&Tag{TypeList, &List{TypeCompound, []*OrderedCompound{reversed}}},
+			true,
+		},
+		{&Tag{TypeCompound, Compound{"a": &Tag{TypeInt, int32(1)}}}, &Tag{TypeCompound, Compound{"b": &Tag{TypeInt, int32(1)}}}, false},
+		{&Tag{TypeIntArray, []int32{1, 2}}, &Tag{TypeIntArray, []int32{1, 3}}, false},
+		{&Tag{TypeByteArray, []byte{}}, &Tag{TypeByteArray, []byte(nil)}, true},
+	}
+
+	for i, test := range tests {
+		if equal := test.a.Equal(test.b); equal != test.equal {
+			t.Errorf("%d: expected %v, got %v", i, test.equal, equal)
+		}
+		if equal := test.b.Equal(test.a); equal != test.equal {
+			t.Errorf("%d: expected %v reversed, got %v", i, test.equal, equal)
+		}
+
+		if test.equal && test.a.Hash() != test.b.Hash() {
+			t.Errorf("%d: hashes differ", i)
+		} else if !test.equal && test.a.Hash() == test.b.Hash() {
+			t.Errorf("%d: hashes equal", i)
+		}
+	}
+}
+
+func TestHashStable(t *testing.T) {
+	tag := &NamedTag{TypeCompound, "", Compound{
+		"id":    &Tag{TypeString, "minecraft:diamond_sword"},
+		"Count": &Tag{TypeByte, int8(1)},
+		"tag": &Tag{TypeCompound, Compound{
+			"Damage": &Tag{TypeInt, int32(3)},
+		}},
+	}}
+
+	// the hash must not change between processes or versions
+	const expected = 0x8b31d2998916d0ac
+	if h := tag.Hash(); h != expected {
+		t.Fatalf("expected %#x, got %#x", uint64(expected), h)
+	}
+}
+
+func TestHashNil(t *testing.T) {
+	hashes := []uint64{
+		(*NamedTag)(nil).Hash(),
+		(*Tag)(nil).Hash(),
+		(*List)(nil).Hash(),
+		(*OrderedCompound)(nil).Hash(),
+	}
+	for i, h := range hashes {
+		if h != hashes[0] {
+			t.Errorf("%d: expected %#x, got %#x", i, hashes[0], h)
+		}
+	}
+
+	if h := (&Tag{TypeList, &List{}}).Hash(); h == hashes[0] {
+		t.Errorf("empty list hashes as nil")
+	}
+}
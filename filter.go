@@ -0,0 +1,99 @@
+package nbt
+
+import (
+	"strings"
+)
+
+// Filter decides which tags DecodeFilter decodes. It is called with the path
+// of each tag in a compound and each element of a list, in the form used by
+// DecodeError. Lists of numbers are always decoded whole.
+type Filter func(path string, typ Type) FilterAction
+
+type FilterAction int
+
+const (
+	// FilterSkip skips the tag without decoding it. A skipped list element
+	// is left out of the list.
+	FilterSkip FilterAction = iota
+	// FilterDescend decodes a compound or list, calling the filter for its
+	// tags. Other tags are decoded whole.
+	FilterDescend
+	// FilterKeep decodes the tag whole.
+	FilterKeep
+	// FilterRaw reads the tag into a RawTag without decoding it. List
+	// elements cannot be raw, and are decoded whole instead.
+	FilterRaw
+)
+
+// DecodeFilter is like Decode, but only decodes the tags selected by filter,
+// returning a partial tree. Skipped tags are not allocated, and are seeked
+// over if the input is an io.Seeker.
+func (dec *Decoder) DecodeFilter(filter Filter) (*NamedTag, error) {
+	dec.filter = filter
+	defer func() { dec.filter = nil }()
+	return dec.Decode()
+}
+
+// PathFilter returns a Filter keeping the tags at the given paths, such as
+// Level.xPos, and the compounds and lists leading to them.
+func PathFilter(paths ...string) Filter {
+	return func(path string, typ Type) FilterAction {
+		action := FilterSkip
+		for _, p := range paths {
+			if p == path {
+				return FilterKeep
+			}
+
+			if strings.HasPrefix(p, path) && (p[len(path)] == '.' || p[len(path)] == '[') {
+				action = FilterDescend
+			}
+		}
+		return action
+	}
+}
+
+// decodeSelected decodes the next payload, of type typ, unless the filter
+// skips it, in which case ok is false.
+func (dec *Decoder) decodeSelected(typ Type) (payload interface{}, ok bool, err error) {
+	if dec.filter == nil {
+		payload, err = dec.decodePayload()
+		return payload, true, err
+	}
+
+	switch dec.filter(dec.tokens.nextPath(), typ) {
+	case FilterSkip:
+		if dec.tokens.payload != TypeEnd {
+			return nil, false, dec.tokens.Skip()
+		}
+		return nil, false, dec.tokens.skipElement()
+	case FilterRaw:
+		if dec.tokens.payload != TypeEnd {
+			raw, err := dec.readRaw(typ)
+			return raw, true, err
+		}
+		fallthrough
+	case FilterKeep:
+		filter := dec.filter
+		dec.filter = nil
+		defer func() { dec.filter = filter }()
+	}
+
+	payload, err = dec.decodePayload()
+	return payload, true, err
+}
+
+// readRaw reads the payload of the tag returned by the last call to Next as a
+// RawTag.
+func (dec *Decoder) readRaw(typ Type) (RawTag, error) {
+	dec.r.recording = true
+	dec.r.record = []byte{byte(typ)}
+	defer func() {
+		dec.r.recording = false
+		dec.r.record = nil
+	}()
+
+	if err := dec.tokens.Skip(); err != nil {
+		return nil, err
+	}
+	return RawTag(dec.r.record), nil
+}
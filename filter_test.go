@@ -0,0 +1,115 @@
+package nbt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeFilter(t *testing.T) {
+	chunk := benchChunk()
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(chunk); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	entity := chunk.Payload.(Compound)["Level"].Payload.(Compound)["TileEntities"].Payload.(*List).Array.([]Compound)[0]
+
+	filter := PathFilter("Level.xPos", "Level.Status", "Level.Sections[1].Y", "Level.TileEntities[0]")
+	expected := &NamedTag{TypeCompound, "", Compound{
+		"Level": &Tag{TypeCompound, Compound{
+			"xPos":   &Tag{TypeInt, int32(0)},
+			"Status": &Tag{TypeString, "full"},
+			"Sections": &Tag{TypeList, &List{TypeCompound, []Compound{
+				{"Y": &Tag{TypeByte, int8(1)}},
+			}}},
+			"TileEntities": &Tag{TypeList, &List{TypeCompound, []Compound{entity}}},
+		}},
+	}}
+
+	for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+		dec := NewDecoder(r)
+		tag, err := dec.DecodeFilter(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(expected, tag); diff != "" {
+			t.Fatalf("%T: cmp.Diff(expected, got):\n%v", r, diff)
+		}
+
+		if dec.r.offset != int64(len(data)) {
+			t.Errorf("%T: expected offset %d, got %d", r, len(data), dec.r.offset)
+		}
+	}
+
+	// the truncated input is only noticed after the seek
+	_, err := NewDecoder(bytes.NewReader(data[:len(data)/2])).DecodeFilter(filter)
+	if err == nil {
+		t.Fatal("expected error for truncated input")
+	}
+}
+
+func TestDecodeFilterPaths(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	enc.SortCompounds(true)
+	err := enc.Encode(&NamedTag{TypeCompound, "r", Compound{
+		"a": &Tag{TypeInt, int32(1)},
+		"l": &Tag{TypeList, &List{TypeCompound, []Compound{
+			{"x": &Tag{TypeInt, int32(2)}},
+			{"x": &Tag{TypeInt, int32(3)}},
+		}}},
+		"s": &Tag{TypeCompound, Compound{"y": &Tag{TypeString, "z"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	filter := func(path string, typ Type) FilterAction {
+		paths = append(paths, path)
+		if typ == TypeList || typ == TypeCompound {
+			return FilterDescend
+		}
+		return FilterSkip
+	}
+
+	tag, err := NewDecoder(buf).DecodeFilter(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPaths := []string{"r.a", "r.l", "r.l[0]", "r.l[0].x", "r.l[1]", "r.l[1].x", "r.s", "r.s.y"}
+	if diff := cmp.Diff(expectedPaths, paths); diff != "" {
+		t.Errorf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	expected := &NamedTag{TypeCompound, "r", Compound{
+		"l": &Tag{TypeList, &List{TypeCompound, []Compound{{}, {}}}},
+		"s": &Tag{TypeCompound, Compound{}},
+	}}
+	if diff := cmp.Diff(expected, tag); diff != "" {
+		t.Errorf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func BenchmarkDecodeFilter(b *testing.B) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(benchChunk()); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+	filter := PathFilter("Level.xPos", "Level.zPos", "Level.Status")
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := NewDecoder(bytes.NewReader(data)).DecodeFilter(filter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
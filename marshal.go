@@ -0,0 +1,706 @@
+package nbt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Marshal returns the NBT encoding of v as an unnamed root tag.
+//
+// Structs and maps with string keys are encoded as compounds, using the
+// field's nbt struct tag as its name if present (`nbt:"Name,omitempty"`, or
+// `nbt:"-"` to skip it). []byte, []int32 and []int64 are encoded as
+// ByteArray, IntArray and LongArray; other slices and arrays as lists.
+// Unsigned integers are stored as the signed type of the same width.
+// Values of type Tag, NamedTag, List and Compound are encoded as they are.
+func Marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).EncodeValue("", v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the NBT data and stores the result in the value pointed
+// to by v, following the mapping used by Marshal. Integer tags may be stored
+// in any Go integer type that can hold the value, and lists and arrays in any
+// slice or array type whose elements can hold theirs.
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).DecodeValue(v)
+}
+
+func (enc *Encoder) EncodeValue(name string, v interface{}) error {
+	typ, payload, err := marshalValue(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	return enc.Encode(&NamedTag{typ, name, payload})
+}
+
+func (dec *Decoder) DecodeValue(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("invalid unmarshal target (%v)", reflect.TypeOf(v))
+	}
+
+	tag, err := dec.Decode()
+	if err != nil {
+		return err
+	}
+
+	if t, ok := v.(*NamedTag); ok {
+		*t = *tag
+		return nil
+	}
+
+	return unmarshalValue(tag.Type, tag.Payload, rv.Elem(), "")
+}
+
+type UnsupportedTypeError struct {
+	GoType reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported Go type (%v)", e.GoType)
+}
+
+type UnmarshalTypeError struct {
+	Type   Type
+	GoType reflect.Type
+	Field  string
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("cannot unmarshal %v into field %s of Go type %v", e.Type, e.Field, e.GoType)
+	}
+	return fmt.Sprintf("cannot unmarshal %v into Go type %v", e.Type, e.GoType)
+}
+
+var (
+	tagType      = reflect.TypeOf(Tag{})
+	namedTagType = reflect.TypeOf(NamedTag{})
+	listType     = reflect.TypeOf(List{})
+	compoundType = reflect.TypeOf(Compound(nil))
+
+	orderedCompoundType = reflect.TypeOf(OrderedCompound{})
+	rawTagType          = reflect.TypeOf(RawTag(nil))
+)
+
+var payloadTypes = [...]reflect.Type{
+	TypeByte:      reflect.TypeOf(int8(0)),
+	TypeShort:     reflect.TypeOf(int16(0)),
+	TypeInt:       reflect.TypeOf(int32(0)),
+	TypeLong:      reflect.TypeOf(int64(0)),
+	TypeFloat:     reflect.TypeOf(float32(0)),
+	TypeDouble:    reflect.TypeOf(float64(0)),
+	TypeByteArray: reflect.TypeOf([]byte(nil)),
+	TypeString:    reflect.TypeOf(""),
+	TypeList:      reflect.TypeOf((*List)(nil)),
+	TypeCompound:  compoundType,
+	TypeIntArray:  reflect.TypeOf([]int32(nil)),
+	TypeLongArray: reflect.TypeOf([]int64(nil)),
+}
+
+// nbtTypeOf returns the tag type used for values of Go type t, or TypeEnd if
+// it can only be determined from the value itself.
+func nbtTypeOf(t reflect.Type) (Type, error) {
+	switch t {
+	case tagType, namedTagType, rawTagType:
+		return TypeEnd, nil
+	case listType:
+		return TypeList, nil
+	case compoundType, orderedCompoundType:
+		return TypeCompound, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return TypeByte, nil
+	case reflect.Int16, reflect.Uint16:
+		return TypeShort, nil
+	case reflect.Int32, reflect.Uint32:
+		return TypeInt, nil
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		return TypeLong, nil
+	case reflect.Float32:
+		return TypeFloat, nil
+	case reflect.Float64:
+		return TypeDouble, nil
+	case reflect.String:
+		return TypeString, nil
+	case reflect.Slice, reflect.Array:
+		switch t.Elem().Kind() {
+		case reflect.Uint8:
+			return TypeByteArray, nil
+		case reflect.Int32:
+			return TypeIntArray, nil
+		case reflect.Int64:
+			return TypeLongArray, nil
+		}
+		return TypeList, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return TypeEnd, &UnsupportedTypeError{t}
+		}
+		return TypeCompound, nil
+	case reflect.Struct:
+		return TypeCompound, nil
+	case reflect.Ptr:
+		return nbtTypeOf(t.Elem())
+	case reflect.Interface:
+		return TypeEnd, nil
+	}
+
+	return TypeEnd, &UnsupportedTypeError{t}
+}
+
+func marshalValue(v reflect.Value) (Type, interface{}, error) {
+	if !v.IsValid() {
+		return TypeEnd, nil, errors.New("cannot marshal nil value")
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return TypeEnd, nil, errors.Errorf("cannot marshal nil value (%v)", v.Type())
+		}
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case tagType:
+		tag := v.Interface().(Tag)
+		return tag.Type, tag.Payload, nil
+	case namedTagType:
+		tag := v.Interface().(NamedTag)
+		return tag.Type, tag.Payload, nil
+	case listType:
+		l := v.Interface().(List)
+		return TypeList, &l, nil
+	case compoundType:
+		return TypeCompound, v.Interface().(Compound), nil
+	case orderedCompoundType:
+		c := v.Interface().(OrderedCompound)
+		return TypeCompound, &c, nil
+	case rawTagType:
+		raw := v.Interface().(RawTag)
+		return raw.Type(), raw, nil
+	}
+
+	typ, err := nbtTypeOf(v.Type())
+	if err != nil {
+		return TypeEnd, nil, errors.WithStack(err)
+	}
+
+	switch typ {
+	case TypeByte:
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				return typ, int8(1), nil
+			}
+			return typ, int8(0), nil
+		case reflect.Uint8:
+			return typ, int8(v.Uint()), nil
+		}
+		return typ, int8(v.Int()), nil
+	case TypeShort:
+		if v.Kind() == reflect.Uint16 {
+			return typ, int16(v.Uint()), nil
+		}
+		return typ, int16(v.Int()), nil
+	case TypeInt:
+		if v.Kind() == reflect.Uint32 {
+			return typ, int32(v.Uint()), nil
+		}
+		return typ, int32(v.Int()), nil
+	case TypeLong:
+		if v.Kind() == reflect.Uint64 || v.Kind() == reflect.Uint {
+			return typ, int64(v.Uint()), nil
+		}
+		return typ, v.Int(), nil
+	case TypeFloat:
+		return typ, float32(v.Float()), nil
+	case TypeDouble:
+		return typ, v.Float(), nil
+	case TypeString:
+		return typ, v.String(), nil
+	case TypeByteArray:
+		b := make([]byte, v.Len())
+		for i := range b {
+			b[i] = byte(v.Index(i).Uint())
+		}
+		return typ, b, nil
+	case TypeIntArray:
+		a := make([]int32, v.Len())
+		for i := range a {
+			a[i] = int32(v.Index(i).Int())
+		}
+		return typ, a, nil
+	case TypeLongArray:
+		a := make([]int64, v.Len())
+		for i := range a {
+			a[i] = v.Index(i).Int()
+		}
+		return typ, a, nil
+	case TypeList:
+		l, err := marshalList(v)
+		return typ, l, err
+	case TypeCompound:
+		if v.Kind() == reflect.Map {
+			m, err := marshalMap(v)
+			return typ, m, err
+		}
+		m, err := marshalStruct(v)
+		return typ, m, err
+	}
+
+	return TypeEnd, nil, errors.WithStack(&UnsupportedTypeError{v.Type()})
+}
+
+func marshalList(v reflect.Value) (*List, error) {
+	typ, err := nbtTypeOf(v.Type().Elem())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	length := v.Len()
+	if length == 0 {
+		if typ == TypeEnd {
+			return &List{}, nil
+		}
+		return &List{typ, reflect.MakeSlice(reflect.SliceOf(payloadTypes[typ]), 0, 0).Interface()}, nil
+	}
+
+	var array reflect.Value
+	for i := 0; i < length; i++ {
+		elemType, payload, err := marshalValue(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+
+		if elemType < TypeByte || elemType > TypeLongArray || reflect.TypeOf(payload) != payloadTypes[elemType] {
+			return nil, errors.Errorf("invalid payload for type %v (%T)", elemType, payload)
+		}
+
+		if i == 0 {
+			typ = elemType
+			array = reflect.MakeSlice(reflect.SliceOf(payloadTypes[typ]), length, length)
+		} else if elemType != typ {
+			return nil, errors.Errorf("mixed list element types (%v, %v)", typ, elemType)
+		}
+
+		array.Index(i).Set(reflect.ValueOf(payload))
+	}
+
+	return &List{typ, array.Interface()}, nil
+}
+
+func marshalMap(v reflect.Value) (Compound, error) {
+	m := make(Compound, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		elem := iter.Value()
+		if (elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface) && elem.IsNil() {
+			continue
+		}
+
+		typ, payload, err := marshalValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		m[iter.Key().String()] = &Tag{typ, payload}
+	}
+	return m, nil
+}
+
+func marshalStruct(v reflect.Value) (Compound, error) {
+	m := make(Compound)
+	for _, f := range cachedFields(v.Type()) {
+		elem, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+
+		if (elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface) && elem.IsNil() {
+			continue
+		}
+
+		if f.omitEmpty && isEmptyValue(elem) {
+			continue
+		}
+
+		typ, payload, err := marshalValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		m[f.name] = &Tag{typ, payload}
+	}
+	return m, nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func cachedFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return fs.([]field)
+}
+
+// typeFields returns the fields of struct type t, with the fields of
+// untagged embedded structs promoted into it.
+func typeFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("nbt")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			fields = append(fields, typeFields(ft, idx)...)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{name, idx, opts == "omitempty"})
+	}
+	return fields
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but reports false instead
+// of panicking when it would step through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func unmarshalValue(typ Type, payload interface{}, v reflect.Value, field string) error {
+	mismatch := func() error {
+		return errors.WithStack(&UnmarshalTypeError{typ, v.Type(), field})
+	}
+
+	if v.Kind() == reflect.Ptr && v.Type() != payloadTypes[TypeList] {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(typ, payload, v.Elem(), field)
+	}
+
+	if v.Type() == tagType {
+		v.Set(reflect.ValueOf(Tag{typ, payload}))
+		return nil
+	}
+
+	if v.Type() == rawTagType {
+		raw, ok := payload.(RawTag)
+		if !ok {
+			var err error
+			if raw, err = NewRawTag(typ, payload); err != nil {
+				return err
+			}
+		}
+		v.Set(reflect.ValueOf(raw))
+		return nil
+	}
+
+	if raw, ok := payload.(RawTag); ok {
+		var err error
+		if payload, err = raw.Decode(); err != nil {
+			return err
+		}
+	}
+
+	if c, ok := payload.(*OrderedCompound); ok && typ == TypeCompound {
+		if v.Type() == orderedCompoundType {
+			v.Set(reflect.ValueOf(c).Elem())
+			return nil
+		}
+		if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(c))
+			return nil
+		}
+		payload = c.Compound()
+	}
+
+	if typ < TypeByte || typ > TypeLongArray || reflect.TypeOf(payload) != payloadTypes[typ] {
+		return errors.Errorf("invalid payload for type %v (%T)", typ, payload)
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.NumMethod() != 0 {
+			return mismatch()
+		}
+		v.Set(reflect.ValueOf(payload))
+		return nil
+	}
+
+	switch typ {
+	case TypeByte, TypeShort, TypeInt, TypeLong:
+		n := reflect.ValueOf(payload).Int()
+		bits := uint(payloadTypes[typ].Bits())
+		switch v.Kind() {
+		case reflect.Bool:
+			if typ != TypeByte {
+				return mismatch()
+			}
+			v.SetBool(n != 0)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(n) {
+				return mismatch()
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u := uint64(n)
+			if uint(v.Type().Bits()) == bits {
+				// reverse of the bit cast done by marshalValue
+				u &= math.MaxUint64 >> (64 - bits)
+			} else if n < 0 || v.OverflowUint(u) {
+				return mismatch()
+			}
+			v.SetUint(u)
+		default:
+			return mismatch()
+		}
+	case TypeFloat, TypeDouble:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(reflect.ValueOf(payload).Float())
+		default:
+			return mismatch()
+		}
+	case TypeString:
+		if v.Kind() != reflect.String {
+			return mismatch()
+		}
+		v.SetString(payload.(string))
+	case TypeByteArray, TypeIntArray, TypeLongArray, TypeList:
+		if v.Type() == listType {
+			if typ != TypeList {
+				return mismatch()
+			}
+			v.Set(reflect.ValueOf(payload).Elem())
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			if typ != TypeList {
+				return mismatch()
+			}
+			v.Set(reflect.ValueOf(payload))
+			return nil
+		}
+		return unmarshalSequence(typ, payload, v, field)
+	case TypeCompound:
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type() == compoundType {
+				v.Set(reflect.ValueOf(payload))
+				return nil
+			}
+			return unmarshalMap(payload.(Compound), v, field)
+		case reflect.Struct:
+			if v.Type() == orderedCompoundType {
+				v.Set(reflect.ValueOf(OrderCompound(payload.(Compound))).Elem())
+				return nil
+			}
+			return unmarshalStruct(payload.(Compound), v, field)
+		default:
+			return mismatch()
+		}
+	}
+
+	return nil
+}
+
+func unmarshalSequence(typ Type, payload interface{}, v reflect.Value, field string) error {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return errors.WithStack(&UnmarshalTypeError{typ, v.Type(), field})
+	}
+
+	var (
+		elemType Type
+		array    reflect.Value
+	)
+	switch typ {
+	case TypeByteArray:
+		if v.Kind() == reflect.Slice && v.Type().Elem() == payloadTypes[TypeByteArray].Elem() {
+			b := payload.([]byte)
+			s := reflect.MakeSlice(v.Type(), len(b), len(b))
+			reflect.Copy(s, reflect.ValueOf(b))
+			v.Set(s)
+			return nil
+		}
+		elemType, array = TypeByte, reflect.ValueOf(payload)
+	case TypeIntArray:
+		elemType, array = TypeInt, reflect.ValueOf(payload)
+	case TypeLongArray:
+		elemType, array = TypeLong, reflect.ValueOf(payload)
+	case TypeList:
+		l := payload.(*List)
+		elemType = l.Type
+		if l.Length() > 0 {
+			array = reflect.ValueOf(l.Array)
+		}
+	}
+
+	var length int
+	if array.IsValid() {
+		length = array.Len()
+	}
+
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), length, length))
+	} else {
+		v.Set(reflect.Zero(v.Type()))
+		if length > v.Len() {
+			length = v.Len()
+		}
+	}
+
+	for i := 0; i < length; i++ {
+		elem := array.Index(i).Interface()
+		if b, ok := elem.(byte); ok {
+			elem = int8(b)
+		}
+		if err := unmarshalValue(elemType, elem, v.Index(i), fmt.Sprintf("%s[%d]", field, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalMap(m Compound, v reflect.Value, field string) error {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return errors.WithStack(&UnmarshalTypeError{TypeCompound, t, field})
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(m)))
+	}
+
+	for name, tag := range m {
+		elem := reflect.New(t.Elem()).Elem()
+		if err := unmarshalValue(tag.Type, tag.Payload, elem, joinField(field, name)); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), elem)
+	}
+
+	return nil
+}
+
+func unmarshalStruct(m Compound, v reflect.Value, field string) error {
+	fields := cachedFields(v.Type())
+	for name, tag := range m {
+		f := lookupField(fields, name)
+		if f == nil {
+			continue
+		}
+
+		elem := v
+		for i, x := range f.index {
+			if i > 0 && elem.Kind() == reflect.Ptr {
+				if elem.IsNil() {
+					elem.Set(reflect.New(elem.Type().Elem()))
+				}
+				elem = elem.Elem()
+			}
+			elem = elem.Field(x)
+		}
+
+		if err := unmarshalValue(tag.Type, tag.Payload, elem, joinField(field, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupField returns the field with the given name, preferring an exact
+// match over a case-insensitive one.
+func lookupField(fields []field, name string) *field {
+	var fold *field
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, name) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,112 @@
+package nbt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+type testStruct struct {
+	ByteMin               int8      `nbt:"byteMin"`
+	ByteMax               int8      `nbt:"byteMax"`
+	ShortMin              int16     `nbt:"shortMin"`
+	ShortMax              int16     `nbt:"shortMax"`
+	IntMin                int32     `nbt:"intMin"`
+	IntMax                int32     `nbt:"intMax"`
+	LongMin               int64     `nbt:"longMin"`
+	LongMax               int64     `nbt:"longMax"`
+	FloatMax              float32   `nbt:"floatMax"`
+	FloatSmallestNonzero  float32   `nbt:"floatSmallestNonzero"`
+	DoubleMax             float64   `nbt:"doubleMax"`
+	DoubleSmallestNonzero float64   `nbt:"doubleSmallestNonzero"`
+	ByteArray             []byte    `nbt:"byteArray"`
+	String                string    `nbt:"string"`
+	ListEmpty             []Tag     `nbt:"listEmpty"`
+	ListByte              []int8    `nbt:"listByte"`
+	ListShort             []int16   `nbt:"listShort"`
+	ListInt               []Tag     `nbt:"listInt"`
+	ListLong              *List     `nbt:"listLong"`
+	ListFloat             []float32 `nbt:"listFloat"`
+	ListDouble            []float64 `nbt:"listDouble"`
+	ListByteArray         [][]byte  `nbt:"listByteArray"`
+	ListString            []string  `nbt:"listString"`
+	ListList              [][]string
+	ListCompound          []map[string]string `nbt:"listCompound"`
+	ListIntArray          [][]int32           `nbt:"listIntArray"`
+	ListLongArray         [][]int64           `nbt:"listLongArray"`
+	CompoundEmpty         struct{}            `nbt:"compoundEmpty"`
+	IntArray              []int32             `nbt:"intArray"`
+	LongArray             []int64             `nbt:"longArray"`
+}
+
+var testValue = testStruct{
+	ByteMin:               math.MinInt8,
+	ByteMax:               math.MaxInt8,
+	ShortMin:              math.MinInt16,
+	ShortMax:              math.MaxInt16,
+	IntMin:                math.MinInt32,
+	IntMax:                math.MaxInt32,
+	LongMin:               math.MinInt64,
+	LongMax:               math.MaxInt64,
+	FloatMax:              math.MaxFloat32,
+	FloatSmallestNonzero:  math.SmallestNonzeroFloat32,
+	DoubleMax:             math.MaxFloat64,
+	DoubleSmallestNonzero: math.SmallestNonzeroFloat64,
+	ByteArray:             testTag.ToCompound()["byteArray"].ToByteArray(),
+	String:                "Hello, world!",
+	ListEmpty:             []Tag{},
+	ListByte:              []int8{math.MinInt8, math.MaxInt8},
+	ListShort:             []int16{math.MinInt16, math.MaxInt16},
+	ListInt:               []Tag{{TypeInt, int32(math.MinInt32)}, {TypeInt, int32(math.MaxInt32)}},
+	ListLong:              &List{TypeLong, []int64{math.MinInt64, math.MaxInt64}},
+	ListFloat:             []float32{math.SmallestNonzeroFloat32, math.MaxFloat32},
+	ListDouble:            []float64{math.SmallestNonzeroFloat64, math.MaxFloat64},
+	ListByteArray:         [][]byte{{0, 1}, {1, 0}},
+	ListString:            []string{"foo", "bar"},
+	ListList:              [][]string{{"zero"}, {"one"}},
+	ListCompound:          []map[string]string{{"foo": "foo"}, {"bar": "bar"}},
+	ListIntArray:          [][]int32{{0, 1}, {1, 0}},
+	ListLongArray:         [][]int64{{0, 1}, {1, 0}},
+	IntArray:              []int32{math.MinInt32, math.MaxInt32},
+	LongArray:             []int64{math.MinInt64, math.MaxInt64},
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := Marshal(testValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v testStruct
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(testValue, v); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v testStruct
+	if err := Unmarshal(testData, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(testValue, v); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	var v struct {
+		String int32 `nbt:"string"`
+	}
+
+	err := Unmarshal(testData, &v)
+	if _, ok := errors.Cause(err).(*UnmarshalTypeError); !ok {
+		t.Fatalf("expected *UnmarshalTypeError, got %#v", err)
+	}
+}
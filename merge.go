@@ -0,0 +1,174 @@
+package nbt
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ListMergeStrategy is how Merge combines two lists.
+type ListMergeStrategy int
+
+const (
+	// ListReplace replaces lists, as Minecraft does.
+	ListReplace ListMergeStrategy = iota
+	// ListAppend appends the elements of the other list.
+	ListAppend
+	// ListMergeByKey merges compound elements of the other list into the
+	// first element whose tag named MergeOptions.Key is equal, and appends
+	// the others.
+	ListMergeByKey
+)
+
+// MergeOptions are the options of Compound.Merge.
+type MergeOptions struct {
+	Lists ListMergeStrategy
+
+	// Key is the name of the tag identifying elements for ListMergeByKey,
+	// such as Slot for inventories.
+	Key string
+
+	// KeepConflicts keeps conflicting tags rather than overwriting them.
+	KeepConflicts bool
+}
+
+// Merge merges other into m as the /data merge command does: compounds are
+// merged recursively, and any other tag in other is set in m. Tags of other
+// are copied.
+//
+// It returns the conflicts, with paths relative to m: tags whose type differs
+// as ChangeType, and lists whose element type differs that cannot be appended
+// to or merged as ChangeValue. Conflicting tags are overwritten unless
+// KeepConflicts is set. As with assigning to a map, m must not be nil unless
+// other is empty.
+func (m Compound) Merge(other Compound, opts MergeOptions) []Change {
+	mg := &merger{opts: opts}
+	mg.compound("", m, other)
+	return mg.conflicts
+}
+
+// Merge is like Compound.Merge. Tags only in other are added in its order.
+// c must not be nil unless other is empty.
+func (c *OrderedCompound) Merge(other *OrderedCompound, opts MergeOptions) []Change {
+	mg := &merger{opts: opts}
+	mg.compound("", c, other)
+	return mg.conflicts
+}
+
+type merger struct {
+	opts      MergeOptions
+	conflicts []Change
+}
+
+func (mg *merger) conflict(kind ChangeKind, path string, old, new *Tag) *Tag {
+	mg.conflicts = append(mg.conflicts, Change{kind, path, old, new})
+	if mg.opts.KeepConflicts {
+		return old
+	}
+	return new.Clone()
+}
+
+func (mg *merger) compound(path string, dst, src interface{}) {
+	if _, ok := compoundLen(src); !ok {
+		return
+	}
+
+	var names []string
+	eachTag(src, func(name string, tag *Tag) bool {
+		names = append(names, name)
+		return true
+	})
+	if _, ok := src.(Compound); ok {
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		old, tag := getTag(dst, name), getTag(src, name)
+		if tag == nil {
+			continue
+		}
+		if merged := mg.tag(childPath(path, name), old, tag); merged != old {
+			setTag(dst, name, merged)
+		}
+	}
+}
+
+// tag returns the result of merging tag into old, which is old itself if it
+// was merged in place.
+func (mg *merger) tag(path string, old, tag *Tag) *Tag {
+	switch {
+	case old == nil:
+		return tag.Clone()
+	case old.Type != tag.Type:
+		return mg.conflict(ChangeType, path, old, tag)
+	}
+
+	switch old.Type {
+	case TypeCompound:
+		if c, ok := old.Payload.(Compound); ok && c == nil {
+			break
+		}
+		if _, ok := compoundLen(old.Payload); !ok {
+			break
+		}
+		mg.compound(path, old.Payload, tag.Payload)
+		return old
+	case TypeList:
+		a, ok1 := old.Payload.(*List)
+		b, ok2 := tag.Payload.(*List)
+		if !ok1 || !ok2 || a == nil || b == nil || !validList(a) || !validList(b) {
+			break
+		}
+		return mg.list(path, old, tag)
+	}
+	return tag.Clone()
+}
+
+func (mg *merger) list(path string, old, tag *Tag) *Tag {
+	a, b := old.Payload.(*List), tag.Payload.(*List)
+	switch {
+	case mg.opts.Lists == ListReplace, a.Length() == 0:
+		return tag.Clone()
+	case b.Length() == 0:
+		return old
+	case a.Type != b.Type, !reflect.TypeOf(b.Array).Elem().AssignableTo(reflect.TypeOf(a.Array).Elem()):
+		// checked before changing the list, so that it is not left
+		// partially merged
+		return mg.conflict(ChangeValue, path, old, tag)
+	}
+
+	byKey := mg.opts.Lists == ListMergeByKey && a.Type == TypeCompound
+	for j, n := 0, b.Length(); j < n; j++ {
+		elem := &Tag{b.Type, listElem(b, j)}
+
+		if byKey {
+			if i := keyIndex(a, mg.opts.Key, elem.Payload); i >= 0 {
+				mg.compound(path+indexSegment(i), compoundAt(a, i), elem.Payload)
+				continue
+			}
+		}
+
+		// cannot fail, as the element types were checked
+		insertElem(old, a.Length(), elem.Clone())
+	}
+	return old
+}
+
+// keyIndex returns the index of the first compound of l whose tag named key
+// is equal to that of c, or -1 if there is none.
+func keyIndex(l *List, key string, c interface{}) int {
+	if _, ok := compoundLen(c); !ok {
+		return -1
+	}
+
+	k := getTag(c, key)
+	if k == nil {
+		return -1
+	}
+
+	for i, n := 0, l.Length(); i < n; i++ {
+		if e := compoundAt(l, i); e != nil && k.Equal(getTag(e, key)) {
+			return i
+		}
+	}
+	return -1
+}
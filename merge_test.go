@@ -0,0 +1,116 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMerge(t *testing.T) {
+	mustParse := func(s string) Compound {
+		tag, err := ParseSNBT(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag.ToCompound()
+	}
+
+	base := `{a: 1, b: {c: 2b, d: "x"}, l: [{Slot: 0b, n: 1}, {Slot: 1b, n: 1}], t: 1}`
+	other := `{b: {c: 3b, e: 1L}, l: [{Slot: 1b, n: 2}, {Slot: 2b, n: 1}], t: "s", new: [I; 1]}`
+
+	tests := []struct {
+		opts      MergeOptions
+		expected  string
+		conflicts []Change
+	}{
+		{
+			MergeOptions{},
+			`{a: 1, b: {c: 3b, d: "x", e: 1L}, l: [{Slot: 1b, n: 2}, {Slot: 2b, n: 1}], t: "s", new: [I; 1]}`,
+			[]Change{{ChangeType, "t", &Tag{TypeInt, int32(1)}, &Tag{TypeString, "s"}}},
+		},
+		{
+			MergeOptions{Lists: ListAppend, KeepConflicts: true},
+			`{a: 1, b: {c: 3b, d: "x", e: 1L}, l: [{Slot: 0b, n: 1}, {Slot: 1b, n: 1}, {Slot: 1b, n: 2}, {Slot: 2b, n: 1}], t: 1, new: [I; 1]}`,
+			[]Change{{ChangeType, "t", &Tag{TypeInt, int32(1)}, &Tag{TypeString, "s"}}},
+		},
+		{
+			MergeOptions{Lists: ListMergeByKey, Key: "Slot"},
+			`{a: 1, b: {c: 3b, d: "x", e: 1L}, l: [{Slot: 0b, n: 1}, {Slot: 1b, n: 2}, {Slot: 2b, n: 1}], t: "s", new: [I; 1]}`,
+			[]Change{{ChangeType, "t", &Tag{TypeInt, int32(1)}, &Tag{TypeString, "s"}}},
+		},
+	}
+
+	for _, test := range tests {
+		m, o := mustParse(base), mustParse(other)
+		conflicts := m.Merge(o, test.opts)
+
+		if diff := cmp.Diff(mustParse(test.expected), m); diff != "" {
+			t.Errorf("%+v: cmp.Diff(expected, got):\n%v", test.opts, diff)
+		}
+		if diff := cmp.Diff(test.conflicts, conflicts); diff != "" {
+			t.Errorf("%+v: cmp.Diff(expected, got) conflicts:\n%v", test.opts, diff)
+		}
+		if diff := cmp.Diff(mustParse(other), o); diff != "" {
+			t.Errorf("%+v: other changed:\n%v", test.opts, diff)
+		}
+	}
+}
+
+func TestMergeListConflict(t *testing.T) {
+	m := Compound{"l": &Tag{TypeList, &List{TypeInt, []int32{1}}}}
+	other := Compound{"l": &Tag{TypeList, &List{TypeString, []string{"a"}}}}
+
+	conflicts := m.Merge(other, MergeOptions{Lists: ListAppend})
+
+	expected := []Change{{ChangeValue, "l", &Tag{TypeList, &List{TypeInt, []int32{1}}}, other["l"]}}
+	if diff := cmp.Diff(expected, conflicts); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+	if diff := cmp.Diff(other, m); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	// compounds of an ordered list cannot be appended to from an unordered
+	// one, which must leave the list unchanged
+	ordered := func() *Tag {
+		return &Tag{TypeList, &List{TypeCompound, []*OrderedCompound{OrderCompound(Compound{"Slot": &Tag{TypeByte, int8(0)}})}}}
+	}
+	m = Compound{"l": ordered()}
+	other = Compound{"l": &Tag{TypeList, &List{TypeCompound, []Compound{
+		{"Slot": &Tag{TypeByte, int8(0)}, "n": &Tag{TypeInt, int32(1)}},
+		{"Slot": &Tag{TypeByte, int8(1)}},
+	}}}}
+
+	conflicts = m.Merge(other, MergeOptions{Lists: ListMergeByKey, Key: "Slot", KeepConflicts: true})
+	if len(conflicts) != 1 || conflicts[0].Kind != ChangeValue {
+		t.Fatalf("expected a value conflict, got %v", conflicts)
+	}
+	if diff := cmp.Diff(Compound{"l": ordered()}, m); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	if conflicts := Compound(nil).Merge(Compound{}, MergeOptions{}); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+}
+
+func TestMergeOrdered(t *testing.T) {
+	c := NewOrderedCompound()
+	c.Set("b", &Tag{TypeInt, int32(1)})
+
+	other := NewOrderedCompound()
+	other.Set("z", &Tag{TypeInt, int32(2)})
+	other.Set("a", &Tag{TypeInt, int32(3)})
+	other.Set("b", &Tag{TypeInt, int32(4)})
+
+	if conflicts := c.Merge(other, MergeOptions{}); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts %v", conflicts)
+	}
+
+	if diff := cmp.Diff([]string{"b", "z", "a"}, c.Names()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+	if got := c.Get("b").ToInt(); got != 4 {
+		t.Fatalf("expected 4, got %d", got)
+	}
+}
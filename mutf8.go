@@ -0,0 +1,157 @@
+package nbt
+
+import (
+	"unicode/utf8"
+)
+
+// Java Edition writes strings in Java's modified UTF-8, which differs from
+// standard UTF-8 in that NUL is encoded as the two bytes C0 80 and
+// supplementary characters are encoded as a surrogate pair of three byte
+// sequences. Like Java, the decoder also accepts a plain NUL byte and overlong
+// sequences. Unpaired surrogates are kept as their three byte sequences so
+// that they survive a round trip, even though the Go string is then not valid
+// UTF-8.
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeMUTF8(b []byte) (string, bool) {
+	if isASCII(b) {
+		return string(b), true
+	}
+
+	s := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			s = append(s, c)
+			i++
+		case c&0xe0 == 0xc0:
+			if i+1 >= len(b) || b[i+1]&0xc0 != 0x80 {
+				return "", false
+			}
+			s = appendRune(s, rune(c&0x1f)<<6|rune(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0:
+			r, ok := decodeMUTF8Unit(b[i:])
+			if !ok {
+				return "", false
+			}
+			i += 3
+
+			if utf16IsHighSurrogate(r) {
+				if lo, ok := decodeMUTF8Unit(b[i:]); ok && utf16IsLowSurrogate(lo) {
+					s = appendRune(s, ((r-0xd800)<<10|(lo-0xdc00))+0x10000)
+					i += 3
+					continue
+				}
+			}
+
+			if utf16IsHighSurrogate(r) || utf16IsLowSurrogate(r) {
+				s = append(s, b[i-3:i]...)
+			} else {
+				s = appendRune(s, r)
+			}
+		default:
+			return "", false
+		}
+	}
+
+	return string(s), true
+}
+
+// decodeMUTF8Unit decodes a three byte sequence into a UTF-16 code unit.
+func decodeMUTF8Unit(b []byte) (rune, bool) {
+	if len(b) < 3 || b[0]&0xf0 != 0xe0 || b[1]&0xc0 != 0x80 || b[2]&0xc0 != 0x80 {
+		return 0, false
+	}
+	return rune(b[0]&0x0f)<<12 | rune(b[1]&0x3f)<<6 | rune(b[2]&0x3f), true
+}
+
+func utf16IsHighSurrogate(r rune) bool {
+	return 0xd800 <= r && r < 0xdc00
+}
+
+func utf16IsLowSurrogate(r rune) bool {
+	return 0xdc00 <= r && r < 0xe000
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	return append(b, buf[:utf8.EncodeRune(buf[:], r)]...)
+}
+
+func appendMUTF8(b []byte, s string) []byte {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c != 0 && c < utf8.RuneSelf {
+			b = append(b, c)
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == 0:
+			b = append(b, 0xc0, 0x80)
+		case r == utf8.RuneError && size == 1:
+			if i+2 < len(s) && c == 0xed && s[i+1]&0xe0 == 0xa0 && s[i+2]&0xc0 == 0x80 {
+				// unpaired surrogate kept by decodeMUTF8
+				b = append(b, s[i:i+3]...)
+				size = 3
+			} else {
+				b = appendMUTF8Unit(b, utf8.RuneError)
+			}
+		case r < 0x800:
+			b = append(b, 0xc0|byte(r>>6), 0x80|byte(r)&0x3f)
+		case r < 0x10000:
+			b = appendMUTF8Unit(b, r)
+		default:
+			r -= 0x10000
+			b = appendMUTF8Unit(b, 0xd800+(r>>10))
+			b = appendMUTF8Unit(b, 0xdc00+(r&0x3ff))
+		}
+		i += size
+	}
+	return b
+}
+
+// mutf8Len returns the length of s in modified UTF-8, as written by
+// appendMUTF8.
+func mutf8Len(s string) int {
+	n := len(s)
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c != 0 && c < utf8.RuneSelf {
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == 0:
+			n++
+		case r == utf8.RuneError && size == 1:
+			if i+2 < len(s) && c == 0xed && s[i+1]&0xe0 == 0xa0 && s[i+2]&0xc0 == 0x80 {
+				size = 3
+			} else {
+				n += 2
+			}
+		case r >= 0x10000:
+			n += 2
+		}
+		i += size
+	}
+	return n
+}
+
+func appendMUTF8Unit(b []byte, r rune) []byte {
+	return append(b, 0xe0|byte(r>>12), 0x80|byte(r>>6)&0x3f, 0x80|byte(r)&0x3f)
+}
@@ -0,0 +1,48 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var mutf8Tests = []struct {
+	s string
+	b []byte
+}{
+	{"", []byte{}},
+	{"Hello, world!", []byte("Hello, world!")},
+	{"\x00", []byte{0xc0, 0x80}},
+	{"café", []byte{0x63, 0x61, 0x66, 0xc3, 0xa9}},
+	{"€", []byte{0xe2, 0x82, 0xac}},
+	{"\U0001f600", []byte{0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}},
+	{"\xed\xa0\xbd!", []byte{0xed, 0xa0, 0xbd, 0x21}},
+}
+
+func TestMUTF8(t *testing.T) {
+	for _, test := range mutf8Tests {
+		b := appendMUTF8([]byte{}, test.s)
+		if diff := cmp.Diff(test.b, b); diff != "" {
+			t.Errorf("appendMUTF8(%q): cmp.Diff(expected, got):\n%v", test.s, diff)
+		}
+
+		if n := mutf8Len(test.s); n != len(test.b) {
+			t.Errorf("mutf8Len(%q): expected %d, got %d", test.s, len(test.b), n)
+		}
+
+		s, ok := decodeMUTF8(test.b)
+		if !ok {
+			t.Errorf("decodeMUTF8(%x): invalid", test.b)
+		} else if s != test.s {
+			t.Errorf("decodeMUTF8(%x): expected %q, got %q", test.b, test.s, s)
+		}
+	}
+}
+
+func TestMUTF8Invalid(t *testing.T) {
+	for _, b := range [][]byte{{0x80}, {0xc0}, {0xe2, 0x82}, {0xf0, 0x9f, 0x98, 0x80}} {
+		if s, ok := decodeMUTF8(b); ok {
+			t.Errorf("decodeMUTF8(%x): expected invalid, got %q", b, s)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// OrderedCompound is a Compound payload that keeps its tags in insertion
+// order, so that decoding and encoding it reproduces the original bytes. It
+// is used in place of Compound when DecoderOptions.OrderedCompounds is set.
+// The zero value is an empty compound.
+type OrderedCompound struct {
+	names []string
+	tags  map[string]*Tag
+}
+
+func NewOrderedCompound() *OrderedCompound {
+	return &OrderedCompound{}
+}
+
+// OrderCompound returns an OrderedCompound with the tags of m in lexical
+// order.
+func OrderCompound(m Compound) *OrderedCompound {
+	c := &OrderedCompound{names: make([]string, 0, len(m)), tags: make(map[string]*Tag, len(m))}
+	for name, tag := range m {
+		c.names = append(c.names, name)
+		c.tags[name] = tag
+	}
+	sort.Strings(c.names)
+	return c
+}
+
+func (c *OrderedCompound) Len() int {
+	return len(c.names)
+}
+
+// Get returns the named tag, or nil if it is not present.
+func (c *OrderedCompound) Get(name string) *Tag {
+	return c.tags[name]
+}
+
+// Set replaces the named tag in place, or adds it at the end.
+func (c *OrderedCompound) Set(name string, tag *Tag) {
+	if c.tags == nil {
+		c.tags = make(map[string]*Tag)
+	}
+	if _, ok := c.tags[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.tags[name] = tag
+}
+
+// Delete removes the named tag, taking time proportional to the number of
+// tags.
+func (c *OrderedCompound) Delete(name string) {
+	if _, ok := c.tags[name]; !ok {
+		return
+	}
+	delete(c.tags, name)
+
+	for i, n := range c.names {
+		if n == name {
+			c.names = append(c.names[:i], c.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// Names returns the names of the tags in order. The slice must not be
+// modified.
+func (c *OrderedCompound) Names() []string {
+	return c.names
+}
+
+// Compound returns the tags as an unordered Compound sharing the same tags.
+func (c *OrderedCompound) Compound() Compound {
+	m := make(Compound, len(c.names))
+	for name, tag := range c.tags {
+		m[name] = tag
+	}
+	return m
+}
+
+func (c *OrderedCompound) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, name := range c.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value, err := json.Marshal(c.tags[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON keeps the order of the JSON object. Nested compounds are also
+// decoded as OrderedCompound.
+func (c *OrderedCompound) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("expected JSON object (%v)", tok)
+	}
+
+	_c := new(OrderedCompound)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		tag, err := unmarshalTagJSON(raw, true)
+		if err != nil {
+			return err
+		}
+
+		if _c.Get(name) != nil {
+			return fmt.Errorf("duplicate name (%q)", name)
+		}
+		_c.Set(name, tag)
+	}
+
+	*c = *_c
+
+	return nil
+}
+
+// UnmarshalJSONOrdered is like json.Unmarshal into a NamedTag, except that
+// compounds are decoded as OrderedCompound in the order of the JSON objects.
+func UnmarshalJSONOrdered(data []byte) (*NamedTag, error) {
+	_tag := new(jsonNamedTag)
+	if err := json.Unmarshal(data, _tag); err != nil {
+		return nil, err
+	}
+
+	payload, err := payloadUnmarshalJSON(_tag.Type, _tag.Payload, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamedTag{_tag.Type, _tag.Name, payload}, nil
+}
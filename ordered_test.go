@@ -0,0 +1,81 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOrderedCompound(t *testing.T) {
+	c := NewOrderedCompound()
+	c.Set("b", &Tag{TypeByte, int8(1)})
+	c.Set("a", &Tag{TypeByte, int8(2)})
+	c.Set("c", &Tag{TypeByte, int8(3)})
+	c.Set("b", &Tag{TypeByte, int8(4)})
+	c.Delete("a")
+	c.Delete("d")
+
+	if diff := cmp.Diff([]string{"b", "c"}, c.Names()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	if tag := c.Get("b"); tag == nil || tag.ToByte() != 4 {
+		t.Fatalf("unexpected tag %v", tag)
+	}
+	if tag := c.Get("a"); tag != nil {
+		t.Fatalf("expected deleted tag, got %v", tag)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"b":{"type":"Byte","payload":"4"},"c":{"type":"Byte","payload":"3"}}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestOrderedRoundTrip(t *testing.T) {
+	dec := NewDecoderOptions(bytes.NewReader(testData), DecoderOptions{OrderedCompounds: true})
+	tag, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := tag.ToOrderedCompound().Len(); n != len(testTag.ToCompound()) {
+		t.Fatalf("expected %d tags, got %d", len(testTag.ToCompound()), n)
+	}
+
+	data, err := json.Marshal(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err = UnmarshalJSONOrdered(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(tag); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(testData, buf.Bytes()) {
+		t.Fatal("round trip changed the encoding")
+	}
+
+	var v struct {
+		ListCompound []OrderedCompound
+	}
+	if err := Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.ListCompound) != 2 || v.ListCompound[1].Get("bar").ToString() != "bar" {
+		t.Fatalf("unexpected value %+v", v)
+	}
+}
@@ -0,0 +1,438 @@
+package nbt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+type PatchOpKind int
+
+const (
+	// PatchAdd sets the tag of a compound at Path, or inserts an element
+	// into a list or array before index i for a path ending in [i], or at
+	// the end for a path ending in [].
+	PatchAdd PatchOpKind = iota
+	// PatchRemove removes the tags at Path.
+	PatchRemove
+	// PatchReplace replaces the existing tags at Path.
+	PatchReplace
+	// PatchMove removes the only tag at From and adds it at Path.
+	PatchMove
+	// PatchTest fails unless each tag at Path is equal to Value.
+	PatchTest
+)
+
+var patchOpKindNames = []string{
+	PatchAdd:     "add",
+	PatchRemove:  "remove",
+	PatchReplace: "replace",
+	PatchMove:    "move",
+	PatchTest:    "test",
+}
+
+var patchOpKindIDs = map[string]PatchOpKind{
+	"add":     PatchAdd,
+	"remove":  PatchRemove,
+	"replace": PatchReplace,
+	"move":    PatchMove,
+	"test":    PatchTest,
+}
+
+func (kind PatchOpKind) String() string {
+	if kind < 0 || int(kind) >= len(patchOpKindNames) {
+		return "unknown"
+	}
+	return patchOpKindNames[kind]
+}
+
+func (kind PatchOpKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(kind.String())
+}
+
+func (kind *PatchOpKind) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	_kind, ok := patchOpKindIDs[s]
+	if !ok {
+		return errors.Errorf("unknown patch op (%v)", s)
+	}
+
+	*kind = _kind
+
+	return nil
+}
+
+// PatchOp is an operation of a Patch.
+type PatchOp struct {
+	Op PatchOpKind `json:"op"`
+
+	// Path of the tags in the syntax of ParsePath, which may match several
+	// tags. The empty path is the root, which can only be replaced or
+	// tested.
+	Path string `json:"path"`
+
+	// From is the path of the tag to move.
+	From string `json:"from,omitempty"`
+
+	// Value is the tag to add, replace with or test against.
+	Value *Tag `json:"value,omitempty"`
+}
+
+func (op *PatchOp) UnmarshalJSON(data []byte) error {
+	// jsonPatchOp has no UnmarshalJSON method, and Op is a pointer to tell a
+	// missing op from add
+	type jsonPatchOp struct {
+		Op    *PatchOpKind `json:"op"`
+		Path  string       `json:"path"`
+		From  string       `json:"from"`
+		Value *Tag         `json:"value"`
+	}
+
+	_op := new(jsonPatchOp)
+	if err := json.Unmarshal(data, _op); err != nil {
+		return err
+	}
+	if _op.Op == nil {
+		return errors.New("missing op")
+	}
+
+	*op = PatchOp{*_op.Op, _op.Path, _op.From, _op.Value}
+
+	return nil
+}
+
+// Patch is a sequence of operations on a tree, like a JSON Patch with NBT
+// paths. It is encoded as JSON as an array of objects such as
+//
+//	{"op": "replace", "path": "Data.Difficulty", "value": {"type": "Byte", "payload": "2"}}
+//
+// and as SNBT as a list of compounds such as
+//
+//	[{op: "replace", path: "Data.Difficulty", value: 2b}]
+type Patch []PatchOp
+
+// PatchError is returned by Patch.Apply when an operation fails.
+type PatchError struct {
+	// Index of the operation in the patch.
+	Index int
+	Op    PatchOp
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch op %d (%v %s): %v", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+func (e *PatchError) Cause() error {
+	return e.Err
+}
+
+// Apply applies the operations of the patch in order to a copy of the payload
+// of tag, which replaces it only if all of them succeed. Otherwise a
+// PatchError is returned and tag is left unchanged.
+func (p Patch) Apply(tag *NamedTag) error {
+	root := &Tag{tag.Type, clonePayload(tag.Payload)}
+	for i, op := range p {
+		if err := op.apply(root); err != nil {
+			return &PatchError{i, op, err}
+		}
+	}
+
+	tag.Type, tag.Payload = root.Type, root.Payload
+	return nil
+}
+
+func (op *PatchOp) apply(root *Tag) error {
+	switch op.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		if op.Value == nil || op.Value.Type == TypeEnd {
+			return errors.Errorf("missing value")
+		}
+	case PatchRemove, PatchMove:
+	default:
+		return errors.Errorf("unknown patch op (%v)", op.Op)
+	}
+
+	if op.Path == "" {
+		switch op.Op {
+		case PatchReplace:
+			value := op.Value.Clone()
+			root.Type, root.Payload = value.Type, value.Payload
+			return nil
+		case PatchTest:
+			if !root.Equal(op.Value) {
+				return errors.New("test failed")
+			}
+			return nil
+		default:
+			return errors.Errorf("cannot %v the root", op.Op)
+		}
+	}
+
+	path, err := ParsePath(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		return patchAdd(root, path, op.Value)
+	case PatchRemove:
+		n, err := path.Remove(root)
+		if err == nil && n == 0 {
+			err = errors.WithStack(ErrPathNotFound)
+		}
+		return err
+	case PatchReplace:
+		matches := path.match(root, false)
+		if len(matches) == 0 {
+			return errors.WithStack(ErrPathNotFound)
+		}
+		for _, m := range matches {
+			if err := m.set(op.Value.Clone()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case PatchMove:
+		from, err := ParsePath(op.From)
+		if err != nil {
+			return errors.Wrap(err, "from")
+		}
+
+		tag, err := from.Get(root)
+		if err != nil {
+			return errors.Wrap(err, "from")
+		}
+		if _, err := from.Remove(root); err != nil {
+			return errors.Wrap(err, "from")
+		}
+		return patchAdd(root, path, tag)
+	default:
+		tags, err := path.GetAll(root)
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return errors.WithStack(ErrPathNotFound)
+		}
+		for _, tag := range tags {
+			if !tag.Equal(op.Value) {
+				return errors.New("test failed")
+			}
+		}
+		return nil
+	}
+}
+
+// patchAdd adds a copy of tag at path, as described for PatchAdd.
+func patchAdd(root *Tag, path *Path, tag *Tag) error {
+	n := len(path.nodes)
+	last := path.nodes[n-1]
+
+	parents := []pathMatch{{tag: root}}
+	if n > 1 {
+		parents = (&Path{nodes: path.nodes[:n-1]}).match(root, false)
+	}
+	if len(parents) == 0 {
+		return errors.WithStack(ErrPathNotFound)
+	}
+
+	for _, m := range parents {
+		switch {
+		case last.kind == pathChild && last.filter == nil:
+			if _, ok := compoundLen(m.tag.Payload); !ok || m.tag.Type != TypeCompound {
+				return errors.Errorf("cannot add %s to %v", pathName(last.name), m.tag.Type)
+			}
+			setTag(m.tag.Payload, last.name, tag.Clone())
+			continue
+		case last.kind == pathIndex, last.kind == pathElements && last.filter == nil:
+		default:
+			return errors.New("cannot add at a filtered path")
+		}
+
+		count, ok := elemCount(m.tag)
+		if !ok {
+			return errors.Errorf("cannot insert into %v", m.tag.Type)
+		}
+
+		i := count
+		if last.kind == pathIndex {
+			i = last.index
+			if i < 0 {
+				i += count
+			}
+			if i < 0 || i > count {
+				return errors.Errorf("index out of range (%d)", last.index)
+			}
+		}
+
+		if err := insertElem(m.tag, i, tag.Clone()); err != nil {
+			return err
+		}
+
+		// the tag of a list element is a copy, so the payload of an array
+		// has to be set again
+		if m.parent != nil {
+			if err := m.set(m.tag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertElem inserts tag into a list or array before index i.
+func insertElem(list *Tag, i int, tag *Tag) error {
+	switch p := list.Payload.(type) {
+	case *List:
+		if p.Length() == 0 {
+			// a list of compounds holds either Compound or *OrderedCompound
+			// elements, depending on the tag inserted
+			elem := payloadTypes[tag.Type]
+			if _, ok := tag.Payload.(*OrderedCompound); ok && tag.Type == TypeCompound {
+				elem = reflect.TypeOf(tag.Payload)
+			}
+			p.Type = tag.Type
+			p.Array = reflect.MakeSlice(reflect.SliceOf(elem), 0, 1).Interface()
+		}
+		if tag.Type != p.Type {
+			return errors.Errorf("list element type mismatch (%v, %v)", p.Type, tag.Type)
+		}
+
+		v := reflect.ValueOf(tag.Payload)
+		array := reflect.ValueOf(p.Array)
+		if !v.IsValid() || !v.Type().AssignableTo(array.Type().Elem()) {
+			return errors.Errorf("invalid payload for type %v (%T)", tag.Type, tag.Payload)
+		}
+
+		array = reflect.Append(array, v)
+		reflect.Copy(array.Slice(i+1, array.Len()), array.Slice(i, array.Len()-1))
+		array.Index(i).Set(v)
+		p.Array = array.Interface()
+		return nil
+	}
+
+	elem := arrayElemTypes[list.Type]
+	if tag.Type != elem {
+		return errors.Errorf("array element type mismatch (%v, %v)", elem, tag.Type)
+	}
+
+	ok := false
+	switch a := list.Payload.(type) {
+	case []byte:
+		var n int8
+		n, ok = tag.Payload.(int8)
+		list.Payload = append(a[:i], append([]byte{byte(n)}, a[i:]...)...)
+	case []int32:
+		var n int32
+		n, ok = tag.Payload.(int32)
+		list.Payload = append(a[:i], append([]int32{n}, a[i:]...)...)
+	case []int64:
+		var n int64
+		n, ok = tag.Payload.(int64)
+		list.Payload = append(a[:i], append([]int64{n}, a[i:]...)...)
+	}
+	if !ok {
+		return errors.Errorf("invalid payload for type %v (%T)", tag.Type, tag.Payload)
+	}
+	return nil
+}
+
+// FormatSNBT formats the patch as an SNBT list of compounds.
+func (p Patch) FormatSNBT() (string, error) {
+	ops := make([]*OrderedCompound, len(p))
+	for i, op := range p {
+		c := new(OrderedCompound)
+		c.Set("op", &Tag{TypeString, op.Op.String()})
+		c.Set("path", &Tag{TypeString, op.Path})
+		if op.From != "" {
+			c.Set("from", &Tag{TypeString, op.From})
+		}
+		if op.Value != nil {
+			c.Set("value", op.Value)
+		}
+		ops[i] = c
+	}
+	return FormatSNBT(TypeList, &List{TypeCompound, ops})
+}
+
+// ParsePatchSNBT parses a patch in the SNBT form described for Patch.
+func ParsePatchSNBT(s string) (Patch, error) {
+	tag, err := ParseSNBT(s)
+	if err != nil {
+		return nil, err
+	}
+
+	l, ok := tag.Payload.(*List)
+	if tag.Type != TypeList || !ok || (l.Length() > 0 && l.Type != TypeCompound) {
+		return nil, errors.Errorf("patch is not a list of compounds (%v)", tag.Type)
+	}
+
+	var ops []Compound
+	if l.Length() > 0 {
+		ops = l.ToCompound()
+	}
+	p := make(Patch, len(ops))
+	for i, c := range ops {
+		for name, tag := range c {
+			switch name {
+			case "op", "path", "from":
+				if tag.Type != TypeString {
+					return nil, errors.Errorf("patch op %d: %s is not a string (%v)", i, name, tag.Type)
+				}
+			case "value":
+				p[i].Value = tag
+				continue
+			default:
+				return nil, errors.Errorf("patch op %d: unknown tag %s", i, pathName(name))
+			}
+
+			s := tag.ToString()
+			switch name {
+			case "op":
+				kind, ok := patchOpKindIDs[s]
+				if !ok {
+					return nil, errors.Errorf("patch op %d: unknown patch op (%v)", i, s)
+				}
+				p[i].Op = kind
+			case "path":
+				p[i].Path = s
+			case "from":
+				p[i].From = s
+			}
+		}
+
+		if c["op"] == nil {
+			return nil, errors.Errorf("patch op %d: missing op", i)
+		}
+	}
+	return p, nil
+}
+
+// DiffPatch returns a patch that changes the payload of a into that of b,
+// made of the changes found by Diff.
+func DiffPatch(a, b *NamedTag) Patch {
+	d := &differ{patch: true}
+	d.payload("", &Tag{a.Type, a.Payload}, &Tag{b.Type, b.Payload})
+
+	p := make(Patch, len(d.changes))
+	for i, c := range d.changes {
+		p[i].Path = c.Path
+		switch c.Kind {
+		case ChangeAdded, ChangeInserted:
+			p[i].Op, p[i].Value = PatchAdd, c.New.Clone()
+		case ChangeRemoved, ChangeDeleted:
+			p[i].Op = PatchRemove
+		default:
+			p[i].Op, p[i].Value = PatchReplace, c.New.Clone()
+		}
+	}
+	return p
+}
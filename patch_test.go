@@ -0,0 +1,247 @@
+package nbt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestPatchApply(t *testing.T) {
+	patch, err := ParsePatchSNBT(`[
+		{op: "test", path: "Data.Player.minecraft:id", value: 7},
+		{op: "replace", path: "Data.Player.Inventory[].Count", value: 2b},
+		{op: "add", path: "Data.Player.Inventory[1]", value: {Slot: 5b, id: "minecraft:sand", Count: 1b}},
+		{op: "add", path: "Data.Player.UUID[]", value: 5},
+		{op: "add", path: "Data.Player.Tags", value: []},
+		{op: "add", path: "Data.Player.Tags[]", value: "new"},
+		{op: "remove", path: "Data.Player.Inventory[{id: \"minecraft:stone\"}]"},
+		{op: "move", from: "Data.Player.\"a.b\"", path: "Data.Name"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := testPlayer()
+	if err := patch.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := testPlayer()
+	player := expected.ToCompound()["Data"].ToCompound()["Player"].ToCompound()
+	player["Inventory"] = &Tag{TypeList, &List{TypeCompound, []Compound{
+		{"Slot": &Tag{TypeByte, int8(5)}, "id": &Tag{TypeString, "minecraft:sand"}, "Count": &Tag{TypeByte, int8(1)}},
+		{"Slot": &Tag{TypeByte, int8(1)}, "id": &Tag{TypeString, "minecraft:dirt"}, "Count": &Tag{TypeByte, int8(2)}},
+	}}}
+	player["UUID"] = &Tag{TypeIntArray, []int32{1, 2, 3, 4, 5}}
+	player["Tags"] = &Tag{TypeList, &List{TypeString, []string{"new"}}}
+	delete(player, "a.b")
+	expected.ToCompound()["Data"].ToCompound()["Name"] = &Tag{TypeString, "dotted"}
+
+	if diff := cmp.Diff(expected, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestPatchApplyAtomic(t *testing.T) {
+	tests := []struct {
+		patch Patch
+		index int
+		cause error
+	}{
+		{Patch{
+			{Op: PatchReplace, Path: "Data.Player.minecraft:id", Value: &Tag{TypeInt, int32(8)}},
+			{Op: PatchTest, Path: "Data.Player.minecraft:id", Value: &Tag{TypeInt, int32(7)}},
+		}, 1, nil},
+		{Patch{
+			{Op: PatchRemove, Path: "Data.Player.UUID"},
+			{Op: PatchRemove, Path: "Data.Player.Missing"},
+		}, 1, ErrPathNotFound},
+		{Patch{
+			{Op: PatchAdd, Path: "Data.Player.Pos[4]", Value: &Tag{TypeDouble, float64(0)}},
+		}, 0, nil},
+		{Patch{
+			{Op: PatchAdd, Path: "Data.Player.Pos[0]", Value: &Tag{TypeFloat, float32(0)}},
+		}, 0, nil},
+		{Patch{
+			{Op: PatchRemove, Path: ""},
+		}, 0, nil},
+		{Patch{
+			{Op: PatchAdd, Path: "Data.Player.Name"},
+		}, 0, nil},
+		{Patch{
+			{Op: PatchMove, From: "Data.Player", Path: "Data.Player.Old"},
+		}, 0, ErrPathNotFound},
+	}
+
+	for _, test := range tests {
+		tag := testPlayer()
+		err := test.patch.Apply(tag)
+
+		e, ok := err.(*PatchError)
+		if !ok {
+			t.Errorf("%v: expected PatchError, got %v", test.patch, err)
+			continue
+		}
+		if e.Index != test.index {
+			t.Errorf("%v: expected error at op %d, got %v", test.patch, test.index, err)
+		}
+		if test.cause != nil && errors.Cause(err) != test.cause {
+			t.Errorf("%v: expected %v, got %v", test.patch, test.cause, err)
+		}
+
+		if diff := cmp.Diff(testPlayer(), tag); diff != "" {
+			t.Errorf("%v: tag changed:\n%v", test.patch, diff)
+		}
+	}
+}
+
+func TestPatchJSON(t *testing.T) {
+	patch := Patch{
+		{Op: PatchTest, Path: "Data.Version", Value: &Tag{TypeInt, int32(1)}},
+		{Op: PatchMove, From: "Data.Old", Path: "Data.New"},
+		{Op: PatchRemove, Path: `Data."a.b"`},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"op":"test","path":"Data.Version","value":{"type":"Int","payload":"1"}},` +
+		`{"op":"move","path":"Data.New","from":"Data.Old"},` +
+		`{"op":"remove","path":"Data.\"a.b\""}]`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+
+	var got Patch
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(patch, got); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	for _, s := range []string{
+		`[{"path": "a", "value": {"type": "Int", "payload": "1"}}]`,
+		`[{"op": null, "path": "a"}]`,
+		`[{"op": "copy", "path": "a"}]`,
+	} {
+		if err := json.Unmarshal([]byte(s), &got); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestPatchSNBT(t *testing.T) {
+	patch := Patch{
+		{Op: PatchTest, Path: "Data.Version", Value: &Tag{TypeInt, int32(1)}},
+		{Op: PatchMove, From: "Data.Old", Path: "Data.New"},
+	}
+
+	s, err := patch.FormatSNBT()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{op:"test",path:"Data.Version",value:1},{op:"move",path:"Data.New",from:"Data.Old"}]`
+	if s != expected {
+		t.Fatalf("expected %s, got %s", expected, s)
+	}
+
+	got, err := ParsePatchSNBT(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(patch, got); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	for _, s := range []string{`{}`, `[{path: "a"}]`, `[{op: "copy", path: "a"}]`, `[{op: "add", path: 1}]`, `[{op: "add", extra: 1b}]`} {
+		if _, err := ParsePatchSNBT(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	a := testPlayer()
+	b := testPlayer()
+
+	player := b.ToCompound()["Data"].ToCompound()["Player"].ToCompound()
+	player["Inventory"] = &Tag{TypeList, &List{TypeCompound, []Compound{
+		{"Slot": &Tag{TypeByte, int8(1)}, "id": &Tag{TypeString, "minecraft:dirt"}, "Count": &Tag{TypeByte, int8(3)}},
+		{"Slot": &Tag{TypeByte, int8(3)}, "id": &Tag{TypeString, "minecraft:sand"}, "Count": &Tag{TypeByte, int8(1)}},
+	}}}
+	player["Pos"] = &Tag{TypeList, &List{TypeDouble, []float64{64}}}
+	player["UUID"] = &Tag{TypeString, "uuid"}
+	delete(player, "a.b")
+
+	patch := DiffPatch(a, b)
+	if err := patch.Apply(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(b, a); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestPatchRemoveArrayInList(t *testing.T) {
+	tag := &NamedTag{TypeCompound, "", Compound{
+		"a": &Tag{TypeList, &List{TypeLongArray, [][]int64{{1, 2, 3}}}},
+		"b": &Tag{TypeList, &List{TypeByteArray, [][]byte{{1, 2}, {3, 4}}}},
+	}}
+
+	patch := Patch{
+		{Op: PatchRemove, Path: "a[0][0]"},
+		{Op: PatchMove, From: "b[1][0]", Path: "b[0][]"},
+	}
+	if err := patch.Apply(tag); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &NamedTag{TypeCompound, "", Compound{
+		"a": &Tag{TypeList, &List{TypeLongArray, [][]int64{{2, 3}}}},
+		"b": &Tag{TypeList, &List{TypeByteArray, [][]byte{{1, 2, 3}, {4}}}},
+	}}
+	if diff := cmp.Diff(expected, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestPatchOrdered(t *testing.T) {
+	item := func(slot int8) *OrderedCompound {
+		c := NewOrderedCompound()
+		c.Set("Slot", &Tag{TypeByte, slot})
+		c.Set("id", &Tag{TypeString, "minecraft:stone"})
+		return c
+	}
+	tree := func(items ...*OrderedCompound) *NamedTag {
+		root := NewOrderedCompound()
+		root.Set("l", &Tag{TypeList, &List{TypeCompound, items}})
+		root.Set("e", &Tag{TypeList, &List{TypeEnd, nil}})
+		return &NamedTag{TypeCompound, "", root}
+	}
+
+	a := tree()
+	b := tree(item(0), item(1))
+	b.Payload.(*OrderedCompound).Set("e", &Tag{TypeList, &List{TypeCompound, []*OrderedCompound{item(2)}}})
+
+	patch := DiffPatch(a, b)
+	if err := patch.Apply(a); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(b, a); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	// the list must still encode as a list of compounds
+	if _, err := EncodedSize(a); err != nil {
+		t.Fatal(err)
+	}
+}
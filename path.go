@@ -0,0 +1,574 @@
+package nbt
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Path is an NBT path as used by Minecraft commands, such as
+// Inventory[{Slot:0b}].tag.display.Name. It consists of:
+//
+//	name or "quoted name"  the tag of a compound with that name
+//	name{...}              the same, if it is a compound matching {...}
+//	{...}                  the root, if it is a compound matching {...}
+//	[i]                    element i of a list or array, counting from the
+//	                       end if negative
+//	[]                     every element of a list or array
+//	[{...}]                every compound in a list matching {...}
+//
+// A compound matches {...} if it has every tag of it, where compounds match
+// in the same way and lists match if each of their elements matches one of
+// the other list.
+//
+// The operations accept a root of type *NamedTag, *Tag, Compound,
+// *OrderedCompound or *List. The name of a NamedTag is not part of paths.
+type Path struct {
+	s     string
+	nodes []pathNode
+}
+
+type pathNodeKind int
+
+const (
+	pathChild pathNodeKind = iota
+	pathRoot
+	pathIndex
+	pathElements
+)
+
+type pathNode struct {
+	kind   pathNodeKind
+	name   string
+	index  int
+	filter Compound // optional for pathChild and pathElements
+}
+
+// ErrPathNotFound is returned when a path matches no tags.
+var ErrPathNotFound = errors.New("path not found")
+
+// ParsePath parses an NBT path, returning a SyntaxError if it is invalid.
+func ParsePath(s string) (*Path, error) {
+	p := &snbtParser{s: s}
+	path := &Path{s: s}
+
+	if s == "" {
+		return nil, p.errorf("empty path")
+	}
+
+	for p.i < len(s) {
+		node, err := p.parsePathNode(len(path.nodes) == 0)
+		if err != nil {
+			return nil, err
+		}
+		path.nodes = append(path.nodes, node)
+
+		if p.i < len(s) && s[p.i] != '[' && s[p.i] != '{' {
+			if s[p.i] != '.' {
+				return nil, p.unexpected(`'.', '[' or '{'`)
+			}
+			p.i++
+			if p.i == len(s) {
+				return nil, p.unexpected("name")
+			}
+		}
+	}
+
+	return path, nil
+}
+
+// MustParsePath is like ParsePath but panics if the path is invalid.
+func MustParsePath(s string) *Path {
+	path, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+func (path *Path) String() string {
+	return path.s
+}
+
+func isPathNameChar(c byte) bool {
+	switch c {
+	case ' ', '"', '\'', '[', ']', '.', '{', '}':
+		return false
+	}
+	return true
+}
+
+func (p *snbtParser) parsePathNode(first bool) (pathNode, error) {
+	switch c := p.s[p.i]; c {
+	case '{':
+		if !first {
+			return pathNode{}, p.errorf("root compound filter not at start")
+		}
+
+		m, err := p.parseCompound()
+		if err != nil {
+			return pathNode{}, err
+		}
+		return pathNode{kind: pathRoot, filter: m}, nil
+	case '[':
+		p.i++
+		switch p.peek() {
+		case ']':
+			p.i++
+			return pathNode{kind: pathElements}, nil
+		case '{':
+			m, err := p.parseCompound()
+			if err != nil {
+				return pathNode{}, err
+			}
+			if err := p.expect(']'); err != nil {
+				return pathNode{}, err
+			}
+			return pathNode{kind: pathElements, filter: m}, nil
+		}
+
+		start := p.i
+		if p.i < len(p.s) && p.s[p.i] == '-' {
+			p.i++
+		}
+		for p.i < len(p.s) && '0' <= p.s[p.i] && p.s[p.i] <= '9' {
+			p.i++
+		}
+
+		index, err := strconv.Atoi(p.s[start:p.i])
+		if err != nil {
+			p.i = start
+			return pathNode{}, p.unexpected("index")
+		}
+		if err := p.expect(']'); err != nil {
+			return pathNode{}, err
+		}
+		return pathNode{kind: pathIndex, index: index}, nil
+	}
+
+	var name string
+	if c := p.s[p.i]; c == '"' || c == '\'' {
+		var err error
+		if name, err = p.parseQuoted(); err != nil {
+			return pathNode{}, err
+		}
+	} else {
+		start := p.i
+		for p.i < len(p.s) && isPathNameChar(p.s[p.i]) {
+			p.i++
+		}
+		if p.i == start {
+			return pathNode{}, p.unexpected("name")
+		}
+		name = p.s[start:p.i]
+	}
+
+	node := pathNode{kind: pathChild, name: name}
+	if p.i < len(p.s) && p.s[p.i] == '{' {
+		m, err := p.parseCompound()
+		if err != nil {
+			return pathNode{}, err
+		}
+		node.filter = m
+	}
+	return node, nil
+}
+
+// pathMatch is a tag matched by a path. The tag of a list or array element is
+// a copy holding the element.
+type pathMatch struct {
+	tag *Tag
+
+	// parent is the Compound, *OrderedCompound, *List or array *Tag holding
+	// the tag, or nil for the root
+	parent interface{}
+	name   string
+	index  int
+
+	// array is the list holding the array parent at arrayIndex, if any,
+	// since the array tag is then a copy
+	array      *List
+	arrayIndex int
+
+	// create holds the missing compounds along the path, which are only
+	// added to the tree when the tag is set
+	create []pathCreate
+}
+
+// pathCreate is a compound to add to parent when setting a tag below it.
+type pathCreate struct {
+	parent interface{}
+	name   string
+	tag    *Tag
+}
+
+// GetAll returns the tags matched by path, which share their payloads with
+// root unless they are numbers or strings in a list or array.
+func (path *Path) GetAll(root interface{}) ([]*Tag, error) {
+	tag, err := rootTag(root)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := path.match(tag, false)
+	tags := make([]*Tag, len(matches))
+	for i, m := range matches {
+		tags[i] = m.tag
+	}
+	return tags, nil
+}
+
+// Get returns the only tag matched by path, or an error if it matches none or
+// more than one.
+func (path *Path) Get(root interface{}) (*Tag, error) {
+	tags, err := path.GetAll(root)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(tags) {
+	case 0:
+		return nil, errors.WithStack(ErrPathNotFound)
+	case 1:
+		return tags[0], nil
+	default:
+		return nil, errors.Errorf("path matches more than one tag (%d)", len(tags))
+	}
+}
+
+// Set replaces each tag matched by path with a copy of tag, creating missing
+// compounds along the way as Minecraft does, and returns the number of tags
+// set. Elements of a list or array must have its element type. Missing
+// compounds are only created for the tags set. If setting a tag fails, the
+// number of tags already set is returned with the error, and they are not
+// restored.
+func (path *Path) Set(root interface{}, tag *Tag) (int, error) {
+	if tag == nil || tag.Type == TypeEnd {
+		return 0, errors.New("cannot set End tag")
+	}
+
+	rt, err := rootTag(root)
+	if err != nil {
+		return 0, err
+	}
+
+	matches := path.match(rt, true)
+	if len(matches) == 0 {
+		return 0, errors.WithStack(ErrPathNotFound)
+	}
+
+	for i, m := range matches {
+		for _, c := range m.create {
+			setTag(c.parent, c.name, c.tag)
+		}
+		if err := m.set(tag.Clone()); err != nil {
+			return i, err
+		}
+	}
+	return len(matches), nil
+}
+
+// Remove removes the tags matched by path and returns their number.
+func (path *Path) Remove(root interface{}) (int, error) {
+	rt, err := rootTag(root)
+	if err != nil {
+		return 0, err
+	}
+
+	matches := path.match(rt, false)
+
+	// remove later elements first, so that indices stay valid
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].index > matches[j].index
+	})
+
+	for i, m := range matches {
+		if err := m.remove(); err != nil {
+			return i, err
+		}
+	}
+
+	if t, ok := root.(*NamedTag); ok {
+		t.Payload = rt.Payload
+	}
+	return len(matches), nil
+}
+
+// rootTag returns the root of a path operation as a Tag.
+func rootTag(root interface{}) (*Tag, error) {
+	switch r := root.(type) {
+	case *NamedTag:
+		return &Tag{r.Type, r.Payload}, nil
+	case *Tag:
+		return r, nil
+	case Compound:
+		return &Tag{TypeCompound, r}, nil
+	case *OrderedCompound:
+		return &Tag{TypeCompound, r}, nil
+	case *List:
+		return &Tag{TypeList, r}, nil
+	default:
+		return nil, errors.Errorf("unsupported path root (%T)", root)
+	}
+}
+
+// match returns the tags matched by path. If create is set, missing tags of
+// compounds are matched with a nil tag, and missing compounds along the way
+// are matched as new compounds to be created as described for pathMatch.
+func (path *Path) match(root *Tag, create bool) []pathMatch {
+	matches := []pathMatch{{tag: root}}
+	for i, node := range path.nodes {
+		last := i == len(path.nodes)-1
+
+		var next []pathMatch
+		for _, m := range matches {
+			if m.tag == nil {
+				continue
+			}
+			n := len(next)
+			next = node.match(next, m, create, last)
+			for k := n; k < len(next); k++ {
+				next[k].create = append(m.create[:len(m.create):len(m.create)], next[k].create...)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// match appends the matches of node in tag to matches.
+func (node *pathNode) match(matches []pathMatch, m pathMatch, create, last bool) []pathMatch {
+	tag := m.tag
+	switch node.kind {
+	case pathRoot:
+		if filterMatch(TypeCompound, node.filter, tag.Type, tag.Payload) {
+			matches = append(matches, pathMatch{tag: tag})
+		}
+	case pathChild:
+		if _, ok := compoundLen(tag.Payload); !ok || tag.Type != TypeCompound {
+			break
+		}
+
+		child := getTag(tag.Payload, node.name)
+		if child == nil && create {
+			missing := pathMatch{parent: tag.Payload, name: node.name}
+			if !last {
+				missing.tag = &Tag{TypeCompound, newCompound(tag.Payload, node.filter.Clone())}
+				missing.create = []pathCreate{{tag.Payload, node.name, missing.tag}}
+			}
+			matches = append(matches, missing)
+			break
+		}
+
+		if child != nil && (node.filter == nil || filterMatch(TypeCompound, node.filter, child.Type, child.Payload)) {
+			matches = append(matches, pathMatch{tag: child, parent: tag.Payload, name: node.name})
+		}
+	case pathIndex:
+		n, ok := elemCount(tag)
+		if !ok {
+			break
+		}
+
+		i := node.index
+		if i < 0 {
+			i += n
+		}
+		if 0 <= i && i < n {
+			matches = append(matches, elemMatch(m, i))
+		}
+	case pathElements:
+		n, _ := elemCount(tag)
+		for i := 0; i < n; i++ {
+			em := elemMatch(m, i)
+			if node.filter == nil || filterMatch(TypeCompound, node.filter, em.tag.Type, em.tag.Payload) {
+				matches = append(matches, em)
+			}
+		}
+	}
+	return matches
+}
+
+// newCompound returns a compound with the tags of c, ordered if parent is.
+func newCompound(parent interface{}, c Compound) interface{} {
+	if _, ok := parent.(*OrderedCompound); ok {
+		return OrderCompound(c)
+	}
+	if c == nil {
+		return make(Compound)
+	}
+	return c
+}
+
+// elemCount returns the number of elements of a list or array.
+func elemCount(tag *Tag) (int, bool) {
+	switch p := tag.Payload.(type) {
+	case *List:
+		return p.Length(), tag.Type == TypeList && validList(p)
+	case []byte:
+		return len(p), tag.Type == TypeByteArray
+	case []int32:
+		return len(p), tag.Type == TypeIntArray
+	case []int64:
+		return len(p), tag.Type == TypeLongArray
+	}
+	return 0, false
+}
+
+// elemMatch returns element i of the list or array matched by outer.
+func elemMatch(outer pathMatch, i int) pathMatch {
+	tag := outer.tag
+	m := pathMatch{parent: tag, index: i}
+	if l, ok := outer.parent.(*List); ok {
+		m.array, m.arrayIndex = l, outer.index
+	}
+
+	switch p := tag.Payload.(type) {
+	case *List:
+		m.parent = p
+		m.tag = &Tag{p.Type, listElem(p, i)}
+	case []byte:
+		m.tag = &Tag{TypeByte, int8(p[i])}
+	case []int32:
+		m.tag = &Tag{TypeInt, p[i]}
+	case []int64:
+		m.tag = &Tag{TypeLong, p[i]}
+	}
+	return m
+}
+
+func (m *pathMatch) set(tag *Tag) error {
+	switch parent := m.parent.(type) {
+	case nil:
+		return errors.New("cannot set the root")
+	case Compound, *OrderedCompound:
+		setTag(parent, m.name, tag)
+		return nil
+	case *List:
+		if tag.Type != parent.Type {
+			return errors.Errorf("list element type mismatch (%v, %v)", parent.Type, tag.Type)
+		}
+
+		v := reflect.ValueOf(tag.Payload)
+		array := reflect.ValueOf(parent.Array)
+		if !v.IsValid() || !v.Type().AssignableTo(array.Type().Elem()) {
+			return errors.Errorf("invalid payload for type %v (%T)", tag.Type, tag.Payload)
+		}
+		array.Index(m.index).Set(v)
+		return nil
+	case *Tag:
+		elem := arrayElemTypes[parent.Type]
+		if tag.Type != elem {
+			return errors.Errorf("array element type mismatch (%v, %v)", elem, tag.Type)
+		}
+
+		ok := false
+		switch a := parent.Payload.(type) {
+		case []byte:
+			var n int8
+			if n, ok = tag.Payload.(int8); ok {
+				a[m.index] = byte(n)
+			}
+		case []int32:
+			var n int32
+			if n, ok = tag.Payload.(int32); ok {
+				a[m.index] = n
+			}
+		case []int64:
+			var n int64
+			if n, ok = tag.Payload.(int64); ok {
+				a[m.index] = n
+			}
+		}
+		if !ok {
+			return errors.Errorf("invalid payload for type %v (%T)", tag.Type, tag.Payload)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (m *pathMatch) remove() error {
+	switch parent := m.parent.(type) {
+	case nil:
+		return errors.New("cannot remove the root")
+	case Compound:
+		delete(parent, m.name)
+	case *OrderedCompound:
+		parent.Delete(m.name)
+	case *List:
+		array := reflect.ValueOf(parent.Array)
+		parent.Array = reflect.AppendSlice(array.Slice(0, m.index), array.Slice(m.index+1, array.Len())).Interface()
+	case *Tag:
+		switch a := parent.Payload.(type) {
+		case []byte:
+			parent.Payload = append(a[:m.index], a[m.index+1:]...)
+		case []int32:
+			parent.Payload = append(a[:m.index], a[m.index+1:]...)
+		case []int64:
+			parent.Payload = append(a[:m.index], a[m.index+1:]...)
+		}
+
+		if m.array != nil {
+			reflect.ValueOf(m.array.Array).Index(m.arrayIndex).Set(reflect.ValueOf(parent.Payload))
+		}
+	}
+	return nil
+}
+
+// setTag sets a tag of a Compound or *OrderedCompound payload.
+func setTag(payload interface{}, name string, tag *Tag) {
+	if c, ok := payload.(*OrderedCompound); ok {
+		c.Set(name, tag)
+		return
+	}
+	payload.(Compound)[name] = tag
+}
+
+// filterMatch reports whether the payload matches the filter payload, as
+// described for Path.
+func filterMatch(filterType Type, filter interface{}, typ Type, payload interface{}) bool {
+	if filterType != typ {
+		return false
+	}
+
+	switch filterType {
+	case TypeCompound:
+		if _, ok := compoundLen(payload); !ok {
+			return false
+		}
+
+		match := true
+		eachTag(filter, func(name string, f *Tag) bool {
+			tag := getTag(payload, name)
+			match = tag != nil && filterMatch(f.Type, f.Payload, tag.Type, tag.Payload)
+			return match
+		})
+		return match
+	case TypeList:
+		fl, ok1 := filter.(*List)
+		l, ok2 := payload.(*List)
+		if !ok1 || !ok2 {
+			return false
+		}
+
+		n, m := fl.Length(), l.Length()
+		if n == 0 {
+			return m == 0
+		}
+
+		for i := 0; i < n; i++ {
+			found := false
+			for j := 0; j < m && !found; j++ {
+				found = filterMatch(fl.Type, listElem(fl, i), l.Type, listElem(l, j))
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return payloadEqual(typ, filter, payload)
+	}
+}
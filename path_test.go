@@ -0,0 +1,222 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func testPlayer() *NamedTag {
+	item := func(slot int8, id string) Compound {
+		return Compound{
+			"Slot":  &Tag{TypeByte, slot},
+			"id":    &Tag{TypeString, id},
+			"Count": &Tag{TypeByte, int8(1)},
+		}
+	}
+
+	return &NamedTag{TypeCompound, "", Compound{
+		"Data": &Tag{TypeCompound, Compound{
+			"Player": &Tag{TypeCompound, Compound{
+				"Pos": &Tag{TypeList, &List{TypeDouble, []float64{1.5, 64, -2.5}}},
+				"Inventory": &Tag{TypeList, &List{TypeCompound, []Compound{
+					item(0, "minecraft:stone"),
+					item(1, "minecraft:dirt"),
+					item(2, "minecraft:stone"),
+				}}},
+				"UUID":         &Tag{TypeIntArray, []int32{1, 2, 3, 4}},
+				"a.b":          &Tag{TypeString, "dotted"},
+				"minecraft:id": &Tag{TypeInt, int32(7)},
+			}},
+		}},
+	}}
+}
+
+func TestPathGet(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []*Tag
+	}{
+		{"Data.Player.Pos[1]", []*Tag{{TypeDouble, float64(64)}}},
+		{"Data.Player.Pos[-1]", []*Tag{{TypeDouble, -2.5}}},
+		{"Data.Player.Pos[3]", []*Tag{}},
+		{"Data.Player.Inventory[{Slot:1b}].id", []*Tag{{TypeString, "minecraft:dirt"}}},
+		{"Data.Player.Inventory[{id:\"minecraft:stone\"}].Slot", []*Tag{{TypeByte, int8(0)}, {TypeByte, int8(2)}}},
+		{"Data.Player.Inventory[].Count", []*Tag{{TypeByte, int8(1)}, {TypeByte, int8(1)}, {TypeByte, int8(1)}}},
+		{"Data.Player.Inventory[{Slot:1}].id", []*Tag{}},
+		{`Data.Player."a.b"`, []*Tag{{TypeString, "dotted"}}},
+		{"Data.Player.'a.b'", []*Tag{{TypeString, "dotted"}}},
+		{"Data.Player.minecraft:id", []*Tag{{TypeInt, int32(7)}}},
+		{"Data.Player.UUID[2]", []*Tag{{TypeInt, int32(3)}}},
+		{"Data{Player:{UUID:[I;1,2,3,4]}}.Player.minecraft:id", []*Tag{{TypeInt, int32(7)}}},
+		{"Data{Player:{UUID:[I;1,2,3]}}.Player", []*Tag{}},
+		{"{Data:{Player:{Pos:[64d]}}}.Data.Player.minecraft:id", []*Tag{{TypeInt, int32(7)}}},
+		{"{Data:{Player:{Pos:[65d]}}}.Data", []*Tag{}},
+		{"Data.Missing.Pos[0]", []*Tag{}},
+		{"Data.Player.Pos.x", []*Tag{}},
+	}
+
+	for _, test := range tests {
+		tags, err := MustParsePath(test.path).GetAll(testPlayer())
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+			continue
+		}
+
+		if diff := cmp.Diff(test.expected, tags); diff != "" {
+			t.Errorf("%s: cmp.Diff(expected, got):\n%v", test.path, diff)
+		}
+	}
+
+	if _, err := MustParsePath("Data.Player.Inventory[].id").Get(testPlayer()); err == nil {
+		t.Error("expected error for more than one match")
+	}
+	if _, err := MustParsePath("Data.Missing").Get(testPlayer()); errors.Cause(err) != ErrPathNotFound {
+		t.Errorf("expected ErrPathNotFound, got %v", err)
+	}
+}
+
+func TestPathSet(t *testing.T) {
+	player := testPlayer()
+	set := func(path string, tag *Tag) int {
+		n, err := MustParsePath(path).Set(player, tag)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return n
+	}
+
+	if n := set("Data.Player.Inventory[].Count", &Tag{TypeByte, int8(64)}); n != 3 {
+		t.Errorf("expected 3 tags set, got %d", n)
+	}
+	set("Data.Player.Pos[0]", &Tag{TypeDouble, float64(0)})
+	set("Data.Player.UUID[-1]", &Tag{TypeInt, int32(5)})
+	set("Data.New.Nested.Value", &Tag{TypeString, "created"})
+
+	get := func(path string) *Tag {
+		tag, err := MustParsePath(path).Get(player)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return tag
+	}
+
+	if !get("Data.Player.Inventory[2].Count").Equal(&Tag{TypeByte, int8(64)}) {
+		t.Error("Count not set")
+	}
+	if !get("Data.Player.Pos").Equal(&Tag{TypeList, &List{TypeDouble, []float64{0, 64, -2.5}}}) {
+		t.Error("Pos[0] not set")
+	}
+	if !get("Data.Player.UUID").Equal(&Tag{TypeIntArray, []int32{1, 2, 3, 5}}) {
+		t.Error("UUID[-1] not set")
+	}
+	if !get("Data.New.Nested.Value").Equal(&Tag{TypeString, "created"}) {
+		t.Error("missing compounds not created")
+	}
+
+	errorTests := []struct {
+		path string
+		tag  *Tag
+	}{
+		{"Data.Player.Pos[0]", &Tag{TypeFloat, float32(0)}},
+		{"Data.Player.UUID[0]", &Tag{TypeLong, int64(0)}},
+		{"Data.Player.UUID[1]", &Tag{TypeInt, int64(0)}},
+		{"Data.Player.Pos[5]", &Tag{TypeDouble, float64(0)}},
+		{"{}", &Tag{TypeCompound, Compound{}}},
+	}
+	for _, test := range errorTests {
+		if _, err := MustParsePath(test.path).Set(player, test.tag); err == nil {
+			t.Errorf("%s: expected error", test.path)
+		}
+	}
+
+	if !get("Data.Player.UUID").Equal(&Tag{TypeIntArray, []int32{1, 2, 3, 5}}) {
+		t.Error("UUID changed by failed sets")
+	}
+}
+
+func TestPathSetCreate(t *testing.T) {
+	root := NewOrderedCompound()
+	root.Set("x", &Tag{TypeInt, int32(1)})
+
+	// nothing to set, so nothing may be created
+	if _, err := MustParsePath("a.b[0]").Set(root, &Tag{TypeByte, int8(1)}); errors.Cause(err) != ErrPathNotFound {
+		t.Fatalf("expected ErrPathNotFound, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"x"}, root.Names()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	if _, err := MustParsePath("a{k:1b}.b").Set(root, &Tag{TypeByte, int8(1)}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, ok := root.Get("a").Payload.(*OrderedCompound)
+	if !ok {
+		t.Fatalf("expected OrderedCompound, got %T", root.Get("a").Payload)
+	}
+	if diff := cmp.Diff([]string{"k", "b"}, a.Names()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestPathRemove(t *testing.T) {
+	player := testPlayer()
+	remove := func(path string) int {
+		n, err := MustParsePath(path).Remove(player)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return n
+	}
+
+	if n := remove("Data.Player.Inventory[{id:\"minecraft:stone\"}]"); n != 2 {
+		t.Errorf("expected 2 tags removed, got %d", n)
+	}
+	remove("Data.Player.UUID[0]")
+	remove("Data.Player.Pos[]")
+	remove(`Data.Player."a.b"`)
+
+	expected := testPlayer()
+	m := expected.Payload.(Compound)["Data"].Payload.(Compound)["Player"].Payload.(Compound)
+	inventory := m["Inventory"].Payload.(*List)
+	inventory.Array = inventory.Array.([]Compound)[1:2]
+	m["UUID"].Payload = []int32{2, 3, 4}
+	m["Pos"].Payload = &List{TypeDouble, []float64{}}
+	delete(m, "a.b")
+
+	if diff := cmp.Diff(expected, player); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	if n := remove("Data.Missing"); n != 0 {
+		t.Errorf("expected nothing removed, got %d", n)
+	}
+}
+
+func TestPathRemoveArrayInList(t *testing.T) {
+	m := Compound{"a": &Tag{TypeList, &List{TypeIntArray, [][]int32{{1, 2, 3}, {4, 5}}}}}
+
+	if _, err := MustParsePath("a[0][0]").Remove(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MustParsePath("a[1][]").Remove(m); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Compound{"a": &Tag{TypeList, &List{TypeIntArray, [][]int32{{2, 3}, {}}}}}
+	if diff := cmp.Diff(expected, m); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, s := range []string{"", "a.", "a..b", "a[", "a[x]", "a[0", "a.{b:1}", "a b", "[{a:}]", `"a`, "a]"} {
+		if _, err := ParsePath(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		} else if _, ok := errors.Cause(err).(*SyntaxError); !ok {
+			t.Errorf("%q: expected SyntaxError, got %v", s, err)
+		}
+	}
+}
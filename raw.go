@@ -0,0 +1,72 @@
+package nbt
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RawTag is a payload holding the type byte and encoded payload of a tag, so
+// that it can be passed through without decoding it, like json.RawMessage.
+// It is produced by DecodeFilter for tags marked FilterRaw and written
+// verbatim by the Encoder, which only checks that its type byte matches the
+// type of the tag. The encoding must be the same as that of the Encoder.
+// Unmarshal, JSON and SNBT decode it with the default options.
+type RawTag []byte
+
+// NewRawTag encodes payload with the default options.
+func NewRawTag(typ Type, payload interface{}) (RawTag, error) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderOptions(buf, EncoderOptions{NamelessRoot: true})
+	if err := enc.Encode(&NamedTag{typ, "", payload}); err != nil {
+		return nil, err
+	}
+	return RawTag(buf.Bytes()), nil
+}
+
+// Type returns the type byte, or End if raw is empty.
+func (raw RawTag) Type() Type {
+	if len(raw) == 0 {
+		return TypeEnd
+	}
+	return Type(raw[0])
+}
+
+// Decode decodes the payload with the default options.
+func (raw RawTag) Decode() (interface{}, error) {
+	return raw.DecodeOptions(DecoderOptions{})
+}
+
+// DecodeOptions decodes the payload with the given options, except
+// NamelessRoot, which RawTag always is.
+func (raw RawTag) DecodeOptions(opts DecoderOptions) (interface{}, error) {
+	if raw.Type() == TypeEnd {
+		return nil, errors.Errorf("invalid raw tag (%x)", []byte(raw))
+	}
+
+	opts.NamelessRoot = true
+	tag, err := NewDecoderOptions(bytes.NewReader(raw), opts).Decode()
+	if err != nil {
+		return nil, err
+	}
+	return tag.Payload, nil
+}
+
+// RawFilter returns a Filter decoding everything except the tags at the given
+// paths, which are kept as RawTag.
+func RawFilter(paths ...string) Filter {
+	return func(path string, typ Type) FilterAction {
+		action := FilterKeep
+		for _, p := range paths {
+			if p == path {
+				return FilterRaw
+			}
+
+			if strings.HasPrefix(p, path) && (p[len(path)] == '.' || p[len(path)] == '[') {
+				action = FilterDescend
+			}
+		}
+		return action
+	}
+}
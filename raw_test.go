@@ -0,0 +1,89 @@
+package nbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRawTag(t *testing.T) {
+	chunk := benchChunk()
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	enc.SortCompounds(true)
+	if err := enc.Encode(chunk); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	tag, err := NewDecoder(bytes.NewReader(data)).DecodeFilter(RawFilter("Level.TileEntities"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	level := tag.Payload.(Compound)["Level"].Payload.(Compound)
+	raw, ok := level["TileEntities"].Payload.(RawTag)
+	if !ok || raw.Type() != TypeList {
+		t.Fatalf("expected raw list, got %T", level["TileEntities"].Payload)
+	}
+
+	payload, err := raw.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := chunk.Payload.(Compound)["Level"].Payload.(Compound)["TileEntities"].Payload
+	if diff := cmp.Diff(expected, payload); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	buf.Reset()
+	if err := enc.Encode(tag); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("raw tag not written verbatim")
+	}
+
+	size, err := EncodedSize(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(data)) {
+		t.Errorf("EncodedSize: expected %d, got %d", len(data), size)
+	}
+
+	mismatch := &NamedTag{TypeCompound, "", Compound{"x": &Tag{TypeCompound, raw}}}
+	if err := enc.Encode(mismatch); err == nil {
+		t.Error("expected error for raw tag type mismatch")
+	}
+}
+
+func TestRawTagUnmarshal(t *testing.T) {
+	var v struct {
+		Raw RawTag `nbt:"raw"`
+	}
+
+	tag := &NamedTag{TypeCompound, "", Compound{"raw": &Tag{TypeList, &List{TypeString, []string{"a", "b"}}}}}
+	data, err := Marshal(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := RawTag{byte(TypeList), byte(TypeString), 0, 0, 0, 2, 0, 1, 'a', 0, 1, 'b'}
+	if diff := cmp.Diff(expected, v.Raw); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	remarshaled, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, remarshaled) {
+		t.Fatalf("expected %x, got %x", data, remarshaled)
+	}
+}
@@ -0,0 +1,464 @@
+package nbt
+
+import (
+	"io"
+	"strings"
+)
+
+type TokenKind byte
+
+const (
+	// TokenTag starts a named tag in a compound, or the root tag. Its
+	// payload follows unless its Type is End.
+	TokenTag TokenKind = iota
+	// TokenValue holds a Byte, Short, Int, Long, Float, Double or String
+	// payload in Value.
+	TokenValue
+	// TokenArrayStart starts a ByteArray, IntArray or LongArray payload of
+	// Length elements, which follow in one or more TokenArrayChunk tokens.
+	TokenArrayStart
+	// TokenArrayChunk holds consecutive array elements in Value as a
+	// []byte, []int32 or []int64, which is only valid until the next call
+	// to Next.
+	TokenArrayChunk
+	TokenArrayEnd
+	// TokenListStart starts a List payload of Length elements of type Elem,
+	// each of which follows as a payload without a TokenTag.
+	TokenListStart
+	TokenListEnd
+	// TokenCompoundStart starts a Compound payload, whose tags follow until
+	// TokenCompoundEnd.
+	TokenCompoundStart
+	TokenCompoundEnd
+)
+
+var tokenKindNames = []string{
+	TokenTag:           "Tag",
+	TokenValue:         "Value",
+	TokenArrayStart:    "ArrayStart",
+	TokenArrayChunk:    "ArrayChunk",
+	TokenArrayEnd:      "ArrayEnd",
+	TokenListStart:     "ListStart",
+	TokenListEnd:       "ListEnd",
+	TokenCompoundStart: "CompoundStart",
+	TokenCompoundEnd:   "CompoundEnd",
+}
+
+func (kind TokenKind) String() string {
+	if int(kind) >= len(tokenKindNames) {
+		return "Unknown"
+	}
+	return tokenKindNames[kind]
+}
+
+type Token struct {
+	Kind   TokenKind
+	Type   Type
+	Name   string
+	Elem   Type
+	Length int
+	Value  interface{}
+}
+
+// readerChunkSize is the maximum number of elements in a TokenArrayChunk.
+const readerChunkSize = 4096
+
+// Reader reads NBT as a stream of tokens, so that large inputs can be
+// processed or skipped over without holding the whole tree in memory.
+type Reader struct {
+	dec   *Decoder
+	stack []readerFrame
+
+	// payload is the type of the payload following the last TokenTag.
+	payload Type
+
+	// skipping is the number of lists and compounds entered by Skip.
+	skipping int
+
+	// root is the name of the current root tag.
+	root string
+
+	bytes []byte
+	ints  []int32
+	longs []int64
+}
+
+type readerFrame struct {
+	typ       Type // List, Compound or one of the array types
+	elem      Type
+	remaining int
+	length    int
+
+	// name of the last tag read in a compound, if its payload is not done
+	name    string
+	inChild bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return NewReaderOptions(r, DecoderOptions{})
+}
+
+func NewReaderOptions(r io.Reader, opts DecoderOptions) *Reader {
+	return NewDecoderOptions(r, opts).tokens
+}
+
+// Buffered returns a reader of the data read from the input but not yet
+// returned as tokens. The reader is valid until the next call to Next or Skip.
+func (r *Reader) Buffered() io.Reader {
+	return r.dec.Buffered()
+}
+
+// Next returns the next token. At the end of a root tag the next root tag is
+// read, if any.
+func (r *Reader) Next() (Token, error) {
+	if typ := r.payload; typ != TypeEnd {
+		r.payload = TypeEnd
+		return r.readPayloadStart(typ)
+	}
+
+	if len(r.stack) == 0 {
+		return r.readRoot()
+	}
+
+	f := &r.stack[len(r.stack)-1]
+	switch f.typ {
+	case TypeCompound:
+		f.inChild = false
+
+		typ, err := r.dec.readType()
+		if err != nil {
+			return Token{}, err
+		}
+
+		if typ == TypeEnd {
+			r.stack = r.stack[:len(r.stack)-1]
+			return Token{Kind: TokenCompoundEnd, Type: TypeCompound}, nil
+		}
+
+		name, err := r.dec.readString()
+		if err != nil {
+			return Token{}, err
+		}
+		f.name, f.inChild = name, true
+
+		if err := r.dec.checkType(typ); err != nil {
+			return Token{}, err
+		}
+
+		r.payload = typ
+		return Token{Kind: TokenTag, Type: typ, Name: name}, nil
+	case TypeList:
+		if f.remaining == 0 {
+			r.stack = r.stack[:len(r.stack)-1]
+			return Token{Kind: TokenListEnd, Type: TypeList, Elem: f.elem}, nil
+		}
+		f.remaining--
+		return r.readPayloadStart(f.elem)
+	default:
+		if f.remaining == 0 {
+			r.stack = r.stack[:len(r.stack)-1]
+			return Token{Kind: TokenArrayEnd, Type: f.typ}, nil
+		}
+		return r.readChunk(f)
+	}
+}
+
+func (r *Reader) readRoot() (Token, error) {
+	r.root = ""
+
+	typ, err := r.dec.readType()
+	if err != nil {
+		return Token{}, err
+	}
+
+	if typ == TypeEnd {
+		if r.dec.disallowEndRoot {
+			return Token{}, r.dec.errorf("root tag is End")
+		}
+		return Token{Kind: TokenTag}, nil
+	}
+
+	var name string
+	if !r.dec.namelessRoot {
+		if name, err = r.dec.readString(); err != nil {
+			return Token{}, err
+		}
+	}
+	r.root = name
+
+	if err := r.dec.checkType(typ); err != nil {
+		return Token{}, err
+	}
+
+	r.payload = typ
+	return Token{Kind: TokenTag, Type: typ, Name: name}, nil
+}
+
+func (r *Reader) readPayloadStart(typ Type) (Token, error) {
+	switch typ {
+	case TypeByteArray, TypeIntArray, TypeLongArray:
+		length, err := r.dec.readLength()
+		if err != nil {
+			return Token{}, err
+		}
+		r.stack = append(r.stack, readerFrame{typ: typ, remaining: int(length), length: int(length)})
+		return Token{Kind: TokenArrayStart, Type: typ, Length: int(length)}, nil
+	case TypeList:
+		if err := r.enter(); err != nil {
+			return Token{}, err
+		}
+
+		elem, err := r.dec.readType()
+		if err != nil {
+			return Token{}, err
+		}
+
+		length, err := r.dec.readLength()
+		if err != nil {
+			return Token{}, err
+		}
+
+		if elem == TypeEnd {
+			length = 0
+		} else if err := r.dec.checkType(elem); err != nil {
+			return Token{}, err
+		}
+
+		r.stack = append(r.stack, readerFrame{typ: typ, elem: elem, remaining: int(length), length: int(length)})
+		return Token{Kind: TokenListStart, Type: typ, Elem: elem, Length: int(length)}, nil
+	case TypeCompound:
+		if err := r.enter(); err != nil {
+			return Token{}, err
+		}
+		r.stack = append(r.stack, readerFrame{typ: typ})
+		return Token{Kind: TokenCompoundStart, Type: typ}, nil
+	}
+
+	value, err := r.dec.readScalar(typ)
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Kind: TokenValue, Type: typ, Value: value}, nil
+}
+
+// path returns the path of the tag being read.
+func (r *Reader) path() string {
+	var b strings.Builder
+	if r.root != "" {
+		b.WriteString(pathName(r.root))
+	}
+
+	for _, f := range r.stack {
+		switch {
+		case f.typ == TypeCompound && f.inChild:
+			b.WriteByte('.')
+			b.WriteString(pathName(f.name))
+		case f.typ == TypeList && f.remaining < f.length:
+			b.WriteString(indexSegment(f.length - f.remaining - 1))
+		}
+	}
+
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+// enter checks the depth limit before a list or compound is entered.
+func (r *Reader) enter() error {
+	// an array is always the innermost frame, so it cannot contain the list
+	// or compound
+	if max := r.dec.maxDepth; max > 0 && len(r.stack)+r.skipping >= max {
+		return r.dec.wrap(&LimitError{"depth", int64(max)})
+	}
+	return nil
+}
+
+func (r *Reader) readChunk(f *readerFrame) (Token, error) {
+	n := f.remaining
+	if n > readerChunkSize {
+		n = readerChunkSize
+	}
+
+	var chunk interface{}
+	switch f.typ {
+	case TypeByteArray:
+		if r.bytes == nil {
+			r.bytes = make([]byte, readerChunkSize)
+		}
+		chunk = r.bytes[:n]
+	case TypeIntArray:
+		if r.ints == nil {
+			r.ints = make([]int32, readerChunkSize)
+		}
+		chunk = r.ints[:n]
+	case TypeLongArray:
+		if r.longs == nil {
+			r.longs = make([]int64, readerChunkSize)
+		}
+		chunk = r.longs[:n]
+	}
+
+	if err := r.dec.readNumbers(chunk); err != nil {
+		return Token{}, err
+	}
+
+	f.remaining -= n
+	return Token{Kind: TokenArrayChunk, Type: f.typ, Value: chunk}, nil
+}
+
+// Skip skips the payload of the tag returned by the last call to Next if it
+// was a TokenTag, and otherwise the rest of the innermost array, list or
+// compound, including its end token.
+func (r *Reader) Skip() error {
+	if typ := r.payload; typ != TypeEnd {
+		r.payload = TypeEnd
+		return r.skipPayload(typ)
+	}
+
+	if len(r.stack) == 0 {
+		return nil
+	}
+
+	f := r.stack[len(r.stack)-1]
+
+	var err error
+	switch f.typ {
+	case TypeCompound:
+		err = r.skipCompound()
+	case TypeList:
+		err = r.skipElements(f.elem, f.remaining)
+	default:
+		err = r.skipElements(arrayElemTypes[f.typ], f.remaining)
+	}
+
+	r.stack = r.stack[:len(r.stack)-1]
+	return err
+}
+
+var arrayElemTypes = map[Type]Type{
+	TypeByteArray: TypeByte,
+	TypeIntArray:  TypeInt,
+	TypeLongArray: TypeLong,
+}
+
+var payloadSizes = map[Type]int64{
+	TypeByte:   1,
+	TypeShort:  2,
+	TypeInt:    4,
+	TypeLong:   8,
+	TypeFloat:  4,
+	TypeDouble: 8,
+}
+
+func (r *Reader) skipPayload(typ Type) error {
+	switch typ {
+	case TypeByte, TypeShort, TypeFloat, TypeDouble:
+		return r.discard(payloadSizes[typ])
+	case TypeInt, TypeLong:
+		return r.skipElements(typ, 1)
+	case TypeString:
+		return r.dec.skipString()
+	case TypeByteArray, TypeIntArray, TypeLongArray:
+		length, err := r.dec.readLength()
+		if err != nil {
+			return err
+		}
+		return r.skipElements(arrayElemTypes[typ], int(length))
+	case TypeList:
+		if err := r.enter(); err != nil {
+			return err
+		}
+
+		elem, err := r.dec.readType()
+		if err != nil {
+			return err
+		}
+
+		length, err := r.dec.readLength()
+		if err != nil {
+			return err
+		}
+
+		if elem == TypeEnd {
+			return nil
+		}
+
+		r.skipping++
+		defer func() { r.skipping-- }()
+		return r.skipElements(elem, int(length))
+	case TypeCompound:
+		if err := r.enter(); err != nil {
+			return err
+		}
+
+		r.skipping++
+		defer func() { r.skipping-- }()
+		return r.skipCompound()
+	default:
+		return r.dec.errorf("unknown type (%v)", typ)
+	}
+}
+
+func (r *Reader) skipElements(typ Type, n int) error {
+	if size, ok := payloadSizes[typ]; ok && !(r.dec.network && (typ == TypeInt || typ == TypeLong)) {
+		return r.discard(size * int64(n))
+	}
+
+	for i := 0; i < n; i++ {
+		var err error
+		switch typ {
+		case TypeInt:
+			_, err = r.dec.readInt32()
+		case TypeLong:
+			_, err = r.dec.readInt64()
+		default:
+			err = r.skipPayload(typ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reader) skipCompound() error {
+	for {
+		typ, err := r.dec.readType()
+		if err != nil {
+			return err
+		}
+
+		if typ == TypeEnd {
+			return nil
+		}
+
+		if err := r.dec.skipString(); err != nil {
+			return err
+		}
+
+		if err := r.skipPayload(typ); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Reader) discard(n int64) error {
+	return r.dec.wrap(r.dec.r.discard(n))
+}
+
+// skipElement skips the next element of the innermost list.
+func (r *Reader) skipElement() error {
+	f := &r.stack[len(r.stack)-1]
+	f.remaining--
+	return r.skipPayload(f.elem)
+}
+
+// nextPath returns the path of the tag whose payload is read next, which is
+// the tag returned by the last call to Next or the next element of the
+// innermost list.
+func (r *Reader) nextPath() string {
+	if n := len(r.stack); n > 0 && r.payload == TypeEnd && r.stack[n-1].typ == TypeList {
+		f := &r.stack[n-1]
+		f.remaining--
+		defer func() { f.remaining++ }()
+	}
+	return r.path()
+}
@@ -0,0 +1,91 @@
+package nbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReaderSkip(t *testing.T) {
+	r := NewReader(bytes.NewReader(testData))
+
+	if tok, err := r.Next(); err != nil {
+		t.Fatal(err)
+	} else if tok.Kind != TokenTag || tok.Name != "root" {
+		t.Fatalf("expected root tag, got %+v", tok)
+	}
+
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for {
+		tok, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tok.Kind == TokenCompoundEnd {
+			break
+		}
+		names = append(names, tok.Name)
+
+		if tok.Name != "string" {
+			if err := r.Skip(); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		tok, err = r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(Token{Kind: TokenValue, Type: TypeString, Value: "Hello, world!"}, tok); diff != "" {
+			t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+		}
+	}
+
+	if len(names) != len(testTag.ToCompound()) {
+		t.Fatalf("expected %d tags, got %v", len(testTag.ToCompound()), names)
+	}
+
+	if _, err := r.Next(); err == nil {
+		t.Fatal("expected error at end of input")
+	}
+}
+
+func TestReaderChunks(t *testing.T) {
+	a := make([]int64, readerChunkSize*2+1)
+	for i := range a {
+		a[i] = int64(i)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(&NamedTag{TypeLongArray, "a", a}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(buf)
+
+	var kinds []TokenKind
+	for {
+		tok, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kinds = append(kinds, tok.Kind)
+		if tok.Kind == TokenArrayEnd {
+			break
+		}
+	}
+
+	expected := []TokenKind{TokenTag, TokenArrayStart, TokenArrayChunk, TokenArrayChunk, TokenArrayChunk, TokenArrayEnd}
+	if diff := cmp.Diff(expected, kinds); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
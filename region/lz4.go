@@ -0,0 +1,422 @@
+package region
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// Minecraft compresses LZ4 chunks with lz4-java's LZ4BlockOutputStream, which
+// splits the data into blocks of at most 64 KiB. Each block has a header of
+// its own holding the compression method, the compressed and decompressed
+// lengths and a checksum of the decompressed data, and the stream ends with an
+// empty block.
+
+const (
+	lz4Magic      = "LZ4Block"
+	lz4HeaderSize = len(lz4Magic) + 13
+
+	lz4MethodRaw = 0x10
+	lz4MethodLZ4 = 0x20
+
+	// the low bits of the method byte hold the base 2 logarithm of the
+	// block size, minus lz4LevelBase
+	lz4LevelBase = 10
+	lz4Level     = 6
+	lz4BlockSize = 1 << (lz4LevelBase + lz4Level)
+
+	lz4Seed = 0x9747b28c
+)
+
+var errLZ4Corrupt = errors.New("corrupt LZ4 block")
+
+type lz4Reader struct {
+	r    io.Reader
+	src  []byte
+	buf  []byte
+	off  int
+	done bool
+}
+
+func newLZ4Reader(r io.Reader) *lz4Reader {
+	return &lz4Reader{r: r}
+}
+
+func (z *lz4Reader) Read(p []byte) (int, error) {
+	for z.off == len(z.buf) {
+		if z.done {
+			return 0, io.EOF
+		}
+		if err := z.readBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, z.buf[z.off:])
+	z.off += n
+	return n, nil
+}
+
+func (z *lz4Reader) Close() error {
+	return nil
+}
+
+func (z *lz4Reader) readBlock() error {
+	var header [lz4HeaderSize]byte
+	if _, err := io.ReadFull(z.r, header[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return errors.WithStack(err)
+	}
+
+	if string(header[:len(lz4Magic)]) != lz4Magic {
+		return errors.Errorf("invalid LZ4 magic (%q)", header[:len(lz4Magic)])
+	}
+
+	method, level := header[8]&0xf0, header[8]&0x0f
+	compressedLen := int64(int32(binary.LittleEndian.Uint32(header[9:])))
+	originalLen := int64(int32(binary.LittleEndian.Uint32(header[13:])))
+	check := binary.LittleEndian.Uint32(header[17:])
+
+	maxLen := int64(1) << (lz4LevelBase + level)
+	if method != lz4MethodRaw && method != lz4MethodLZ4 ||
+		originalLen < 0 || originalLen > maxLen ||
+		compressedLen < 0 || compressedLen > maxLen+maxLen/255+16 ||
+		method == lz4MethodRaw && compressedLen != originalLen {
+		return errors.Errorf("invalid LZ4 block header (%x)", header[len(lz4Magic):])
+	}
+
+	z.buf = z.buf[:0]
+	z.off = 0
+
+	if originalLen == 0 {
+		if compressedLen != 0 || check != 0 {
+			return errors.Errorf("invalid LZ4 block header (%x)", header[len(lz4Magic):])
+		}
+		z.done = true
+		return nil
+	}
+
+	if int64(cap(z.src)) < compressedLen {
+		z.src = make([]byte, compressedLen)
+	}
+	src := z.src[:compressedLen]
+	if _, err := io.ReadFull(z.r, src); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return errors.WithStack(err)
+	}
+
+	if int64(cap(z.buf)) < originalLen {
+		z.buf = make([]byte, originalLen)
+	}
+	z.buf = z.buf[:originalLen]
+
+	if method == lz4MethodRaw {
+		copy(z.buf, src)
+	} else if n, err := lz4Decompress(z.buf, src); err != nil {
+		return err
+	} else if int64(n) != originalLen {
+		return errors.WithStack(errLZ4Corrupt)
+	}
+
+	if sum := lz4Checksum(z.buf); sum != check {
+		return errors.Errorf("LZ4 checksum mismatch (%#x, %#x)", check, sum)
+	}
+
+	return nil
+}
+
+type lz4Writer struct {
+	w   io.Writer
+	buf []byte
+	out []byte
+}
+
+func newLZ4Writer(w io.Writer) *lz4Writer {
+	return &lz4Writer{w: w, buf: make([]byte, 0, lz4BlockSize)}
+}
+
+func (z *lz4Writer) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if len(z.buf) == cap(z.buf) {
+			if err := z.flush(); err != nil {
+				return n, err
+			}
+		}
+
+		k := copy(z.buf[len(z.buf):cap(z.buf)], p)
+		z.buf = z.buf[:len(z.buf)+k]
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Close writes any buffered data and the empty block ending the stream. It
+// does not close the underlying writer.
+func (z *lz4Writer) Close() error {
+	if err := z.flush(); err != nil {
+		return err
+	}
+
+	var header [lz4HeaderSize]byte
+	lz4PutHeader(header[:], lz4MethodRaw, 0, 0, 0)
+	_, err := z.w.Write(header[:])
+	return errors.WithStack(err)
+}
+
+func (z *lz4Writer) flush() error {
+	if len(z.buf) == 0 {
+		return nil
+	}
+
+	out := lz4Compress(append(z.out[:0], make([]byte, lz4HeaderSize)...), z.buf)
+
+	method := byte(lz4MethodLZ4)
+	if len(out)-lz4HeaderSize >= len(z.buf) {
+		method = lz4MethodRaw
+		out = append(out[:lz4HeaderSize], z.buf...)
+	}
+	lz4PutHeader(out, method, len(out)-lz4HeaderSize, len(z.buf), lz4Checksum(z.buf))
+
+	z.out = out
+	z.buf = z.buf[:0]
+
+	_, err := z.w.Write(out)
+	return errors.WithStack(err)
+}
+
+func lz4PutHeader(b []byte, method byte, compressedLen, originalLen int, check uint32) {
+	copy(b, lz4Magic)
+	b[8] = method | lz4Level
+	binary.LittleEndian.PutUint32(b[9:], uint32(compressedLen))
+	binary.LittleEndian.PutUint32(b[13:], uint32(originalLen))
+	binary.LittleEndian.PutUint32(b[17:], check)
+}
+
+// lz4Checksum is the checksum lz4-java stores in a block header, which is
+// truncated to 28 bits.
+func lz4Checksum(b []byte) uint32 {
+	return xxh32(b, lz4Seed) & 0xfffffff
+}
+
+// lz4Decompress decodes the LZ4 block src into dst, which must be large
+// enough to hold the result, and returns the decompressed length.
+func lz4Decompress(dst, src []byte) (int, error) {
+	var d, s int
+	for {
+		if s == len(src) {
+			return 0, errors.WithStack(errLZ4Corrupt)
+		}
+		token := src[s]
+		s++
+
+		n := int(token >> 4)
+		if n == 15 {
+			var ok bool
+			if n, s, ok = lz4ReadLength(src, s, n, len(dst)); !ok {
+				return 0, errors.WithStack(errLZ4Corrupt)
+			}
+		}
+
+		if n > len(src)-s || n > len(dst)-d {
+			return 0, errors.WithStack(errLZ4Corrupt)
+		}
+		d += copy(dst[d:], src[s:s+n])
+		s += n
+
+		// the last sequence consists of literals only
+		if s == len(src) {
+			return d, nil
+		}
+
+		if len(src)-s < 2 {
+			return 0, errors.WithStack(errLZ4Corrupt)
+		}
+		offset := int(binary.LittleEndian.Uint16(src[s:]))
+		s += 2
+
+		if offset == 0 || offset > d {
+			return 0, errors.WithStack(errLZ4Corrupt)
+		}
+
+		m := int(token & 15)
+		if m == 15 {
+			var ok bool
+			if m, s, ok = lz4ReadLength(src, s, m, len(dst)); !ok {
+				return 0, errors.WithStack(errLZ4Corrupt)
+			}
+		}
+		m += 4
+
+		if m > len(dst)-d {
+			return 0, errors.WithStack(errLZ4Corrupt)
+		}
+
+		if offset >= m {
+			copy(dst[d:d+m], dst[d-offset:])
+		} else {
+			// the match overlaps the bytes it produces
+			for i := d; i < d+m; i++ {
+				dst[i] = dst[i-offset]
+			}
+		}
+		d += m
+	}
+}
+
+// lz4ReadLength reads the bytes extending a literal or match length n, which
+// may not exceed max.
+func lz4ReadLength(src []byte, s, n, max int) (int, int, bool) {
+	for s < len(src) && n <= max {
+		c := src[s]
+		s++
+		n += int(c)
+		if c != 255 {
+			return n, s, true
+		}
+	}
+	return 0, 0, false
+}
+
+const (
+	lz4MinMatch = 4
+	// a match must start at least lz4MFLimit bytes and end at least
+	// lz4LastLiterals bytes before the end of the block
+	lz4MFLimit      = 12
+	lz4LastLiterals = 5
+
+	lz4HashLog = 14
+)
+
+// lz4Compress appends the LZ4 block encoding of src to dst. Matches are found
+// greedily with a hash table of the last position of each four byte sequence.
+func lz4Compress(dst, src []byte) []byte {
+	var table [1 << lz4HashLog]int32
+
+	var anchor int
+	for i := 0; i+lz4MFLimit <= len(src); {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := (seq * xxhPrime1) >> (32 - lz4HashLog)
+
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if ref < 0 || i-ref > 0xffff || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+
+		m := lz4MinMatch
+		for i+m < len(src)-lz4LastLiterals && src[ref+m] == src[i+m] {
+			m++
+		}
+
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, m)
+		i += m
+		anchor = i
+	}
+
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence appends a sequence of literals followed by a match, or
+// only literals if match is 0.
+func lz4AppendSequence(dst, literals []byte, offset, match int) []byte {
+	var token byte
+	if n := len(literals); n >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(n) << 4
+	}
+	if n := match - lz4MinMatch; match != 0 && n >= 15 {
+		token |= 15
+	} else if match != 0 {
+		token |= byte(n)
+	}
+
+	dst = append(dst, token)
+	if n := len(literals); n >= 15 {
+		dst = lz4AppendLength(dst, n-15)
+	}
+	dst = append(dst, literals...)
+
+	if match == 0 {
+		return dst
+	}
+
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if n := match - lz4MinMatch; n >= 15 {
+		dst = lz4AppendLength(dst, n-15)
+	}
+	return dst
+}
+
+func lz4AppendLength(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+const (
+	xxhPrime1 = 2654435761
+	xxhPrime2 = 2246822519
+	xxhPrime3 = 3266489917
+	xxhPrime4 = 668265263
+	xxhPrime5 = 374761393
+)
+
+// xxh32 is the 32-bit xxHash of b.
+func xxh32(b []byte, seed uint32) uint32 {
+	n := len(b)
+
+	var h uint32
+	if n >= 16 {
+		v1 := seed + xxhPrime1 + xxhPrime2
+		v2 := seed + xxhPrime2
+		v3 := seed
+		v4 := seed - xxhPrime1
+
+		for ; len(b) >= 16; b = b[16:] {
+			v1 = xxh32Round(v1, binary.LittleEndian.Uint32(b))
+			v2 = xxh32Round(v2, binary.LittleEndian.Uint32(b[4:]))
+			v3 = xxh32Round(v3, binary.LittleEndian.Uint32(b[8:]))
+			v4 = xxh32Round(v4, binary.LittleEndian.Uint32(b[12:]))
+		}
+
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) +
+			bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxhPrime5
+	}
+
+	h += uint32(n)
+
+	for ; len(b) >= 4; b = b[4:] {
+		h += binary.LittleEndian.Uint32(b) * xxhPrime3
+		h = bits.RotateLeft32(h, 17) * xxhPrime4
+	}
+	for _, c := range b {
+		h += uint32(c) * xxhPrime5
+		h = bits.RotateLeft32(h, 11) * xxhPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxhPrime2
+	h ^= h >> 13
+	h *= xxhPrime3
+	h ^= h >> 16
+
+	return h
+}
+
+func xxh32Round(acc, input uint32) uint32 {
+	return bits.RotateLeft32(acc+input*xxhPrime2, 13) * xxhPrime1
+}
@@ -0,0 +1,116 @@
+package region
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestXXH32(t *testing.T) {
+	tests := []struct {
+		in   string
+		hash uint32
+	}{
+		{"", 0x02cc5d05},
+		{"a", 0x550d7456},
+		{"abc", 0x32d153ff},
+		{"Nobody inspects the spammish repetition", 0xe2293b2f},
+	}
+
+	for _, test := range tests {
+		if hash := xxh32([]byte(test.in), 0); hash != test.hash {
+			t.Errorf("xxh32(%q): expected %#08x, got %#08x", test.in, test.hash, hash)
+		}
+	}
+}
+
+func TestLZ4Decompress(t *testing.T) {
+	// "abc" and a match of 12 bytes at offset 3, then 5 literals
+	src := []byte{0x38, 'a', 'b', 'c', 3, 0, 0x50, 'h', 'e', 'l', 'l', 'o'}
+	expected := strings.Repeat("abc", 5) + "hello"
+
+	dst := make([]byte, len(expected))
+	n, err := lz4Decompress(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(dst[:n]); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	corrupt := [][]byte{
+		src[:2],
+		src[:5],
+		src[:7],
+		{0x30, 'a', 'b', 'c', 4, 0, 0x50},
+		{0x30, 'a', 'b', 'c', 0, 0, 0x50},
+		{0xf0, 255, 255},
+	}
+	for _, b := range corrupt {
+		if _, err := lz4Decompress(dst, b); err == nil {
+			t.Errorf("expected error decompressing %x", b)
+		}
+	}
+}
+
+func TestLZ4(t *testing.T) {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := map[string][]byte{
+		"empty":      nil,
+		"short":      []byte("hello"),
+		"repetitive": bytes.Repeat([]byte("minecraft:stone "), 20000),
+		"random":     random,
+	}
+
+	for name, data := range tests {
+		buf := new(bytes.Buffer)
+		w := newLZ4Writer(buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if name == "repetitive" && buf.Len() > len(data)/10 {
+			t.Errorf("%s: poor compression (%d bytes)", name, buf.Len())
+		}
+
+		got, err := ioutil.ReadAll(newLZ4Reader(bytes.NewReader(buf.Bytes())))
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: data mismatch", name)
+		}
+
+		// corrupt the checksum of the first block
+		b := buf.Bytes()
+		b[lz4HeaderSize-1] ^= 1
+		if _, err := ioutil.ReadAll(newLZ4Reader(bytes.NewReader(b))); err == nil && len(data) > 0 {
+			t.Errorf("%s: expected checksum error", name)
+		}
+	}
+}
+
+func TestLZ4Header(t *testing.T) {
+	w := newLZ4Writer(ioutil.Discard)
+	w.Write([]byte("hello"))
+
+	buf := new(bytes.Buffer)
+	w.w = buf
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("LZ4Block\x16\x05\x00\x00\x00\x05\x00\x00\x00")
+	if diff := cmp.Diff(expected, buf.Bytes()[:lz4HeaderSize-4]); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
@@ -0,0 +1,295 @@
+// Package region reads and writes Anvil (.mca) and McRegion (.mcr) region
+// files, which store the NBT data of 32×32 chunks.
+package region
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/njhanley/nbt"
+	"github.com/pkg/errors"
+)
+
+const (
+	SectorSize = 4096
+
+	// the header consists of a sector of chunk locations followed by a
+	// sector of timestamps
+	headerSize = 2 * SectorSize
+	numChunks  = 32 * 32
+)
+
+type Compression byte
+
+const (
+	CompressionGzip Compression = 1
+	CompressionZlib Compression = 2
+	CompressionNone Compression = 3
+	CompressionLZ4  Compression = 4
+)
+
+// externalFlag is set in the compression byte of a chunk whose data is stored
+// in a separate file because it does not fit in the region file.
+const externalFlag = 0x80
+
+var compressionNames = map[Compression]string{
+	CompressionGzip: "gzip",
+	CompressionZlib: "zlib",
+	CompressionNone: "none",
+	CompressionLZ4:  "lz4",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#02x", byte(c))
+}
+
+var ErrNoChunk = errors.New("chunk not present")
+
+// File is a region file. Chunk coordinates are taken modulo 32, so both
+// region-local and absolute chunk coordinates may be used.
+type File struct {
+	r          io.ReaderAt
+	c          io.Closer
+	name       string
+	locations  [numChunks]uint32
+	timestamps [numChunks]uint32
+
+	// used marks the sectors occupied by the header and chunks
+	used        []bool
+	compression Compression
+}
+
+type ChunkInfo struct {
+	X, Z      int
+	Timestamp time.Time
+
+	// location of the chunk in the file, in sectors
+	Offset  int
+	Sectors int
+}
+
+func Open(name string) (*File, error) {
+	return OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile is like os.OpenFile. The file must be opened for reading, and
+// also for writing in order to use WriteChunk and DeleteChunk. Chunks stored
+// in external files are only supported if the file is named r.X.Z.mca, as
+// their names are derived from the region coordinates.
+func OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	file, err := New(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	file.c = f
+	file.name = name
+
+	return file, nil
+}
+
+// New reads the header of the region file in r. An empty file is an empty
+// region. If r is also an io.WriterAt, chunks can be written.
+func New(r io.ReaderAt) (*File, error) {
+	f := &File{r: r, compression: CompressionZlib}
+
+	var header [headerSize]byte
+	if n, err := r.ReadAt(header[:], 0); err != nil && !(err == io.EOF && (n == 0 || n == headerSize)) {
+		// io.ReaderAt may return io.EOF with a full header at the end of
+		// the input
+		if err == io.EOF {
+			return nil, errors.Errorf("truncated header (%d bytes)", n)
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	for i := range f.locations {
+		f.locations[i] = binary.BigEndian.Uint32(header[4*i:])
+		f.timestamps[i] = binary.BigEndian.Uint32(header[SectorSize+4*i:])
+	}
+
+	f.markUsed(0, headerSize/SectorSize, true)
+	for i := range f.locations {
+		if info := f.Chunk(i%32, i/32); info.Offset != 0 {
+			f.markUsed(info.Offset, info.Sectors, true)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *File) Close() error {
+	if f.c == nil {
+		return nil
+	}
+	return errors.WithStack(f.c.Close())
+}
+
+func index(x, z int) int {
+	return x&31 + (z&31)*32
+}
+
+func (f *File) HasChunk(x, z int) bool {
+	return f.locations[index(x, z)] != 0
+}
+
+func (f *File) Chunk(x, z int) ChunkInfo {
+	i := index(x, z)
+	loc := f.locations[i]
+	return ChunkInfo{
+		X:         x & 31,
+		Z:         z & 31,
+		Timestamp: time.Unix(int64(f.timestamps[i]), 0),
+		Offset:    int(loc >> 8),
+		Sectors:   int(loc & 0xff),
+	}
+}
+
+// Chunks returns the chunks present in the file.
+func (f *File) Chunks() []ChunkInfo {
+	var chunks []ChunkInfo
+	for i, loc := range f.locations {
+		if loc != 0 {
+			chunks = append(chunks, f.Chunk(i%32, i/32))
+		}
+	}
+	return chunks
+}
+
+// ReadChunk decodes the NBT data of chunk (x, z).
+func (f *File) ReadChunk(x, z int) (*nbt.NamedTag, error) {
+	r, err := f.chunkReader(x, z)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return nbt.NewDecoder(r).Decode()
+}
+
+// chunkReader returns the decompressed data of chunk (x, z).
+func (f *File) chunkReader(x, z int) (io.ReadCloser, error) {
+	info := f.Chunk(x, z)
+	if info.Offset == 0 {
+		return nil, errors.WithStack(ErrNoChunk)
+	}
+
+	if info.Offset < headerSize/SectorSize {
+		return nil, errors.Errorf("chunk offset overlaps header (%d)", info.Offset)
+	}
+
+	off := int64(info.Offset) * SectorSize
+
+	var header [5]byte
+	if _, err := f.r.ReadAt(header[:], off); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[:]))
+	if length == 0 || length+4 > int64(info.Sectors)*SectorSize {
+		return nil, errors.Errorf("invalid chunk length (%d)", length)
+	}
+
+	c := Compression(header[4])
+	if c&externalFlag == 0 {
+		return decompress(c, io.NewSectionReader(f.r, off+int64(len(header)), length-1))
+	}
+
+	name, err := f.externalName(x, z)
+	if err != nil {
+		return nil, err
+	}
+
+	ext, err := os.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r, err := decompress(c&^externalFlag, ext)
+	if err != nil {
+		ext.Close()
+		return nil, err
+	}
+	return readCloser{r, ext}, nil
+}
+
+func decompress(c Compression, data io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		r, err := gzip.NewReader(data)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return r, nil
+	case CompressionZlib:
+		r, err := zlib.NewReader(data)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return r, nil
+	case CompressionNone:
+		return ioutil.NopCloser(data), nil
+	case CompressionLZ4:
+		return newLZ4Reader(data), nil
+	default:
+		return nil, errors.Errorf("unknown compression (%v)", c)
+	}
+}
+
+// readCloser closes both the decompressor and the external file it reads.
+type readCloser struct {
+	io.ReadCloser
+	c io.Closer
+}
+
+func (r readCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.c.Close(); err == nil {
+		err = errors.WithStack(cerr)
+	}
+	return err
+}
+
+var regionName = regexp.MustCompile(`^r\.(-?\d+)\.(-?\d+)\.mc[ar]$`)
+
+// externalName returns the name of the file holding the data of chunk (x, z)
+// when it is too large for the region file. It is in the same directory as
+// the region file and named c.X.Z.mcc after the absolute chunk coordinates.
+func (f *File) externalName(x, z int) (string, error) {
+	m := regionName.FindStringSubmatch(filepath.Base(f.name))
+	if m == nil {
+		return "", errors.Errorf("external chunk requires a region file named r.X.Z.mca (%q)", f.name)
+	}
+
+	rx, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	rz, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	name := fmt.Sprintf("c.%d.%d.mcc", rx*32+x&31, rz*32+z&31)
+	return filepath.Join(filepath.Dir(f.name), name), nil
+}
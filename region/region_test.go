@@ -0,0 +1,151 @@
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/njhanley/nbt"
+	"github.com/pkg/errors"
+)
+
+func testChunk(x, z int) *nbt.NamedTag {
+	return &nbt.NamedTag{Type: nbt.TypeCompound, Payload: nbt.Compound{
+		"xPos":   &nbt.Tag{Type: nbt.TypeInt, Payload: int32(x)},
+		"zPos":   &nbt.Tag{Type: nbt.TypeInt, Payload: int32(z)},
+		"Status": &nbt.Tag{Type: nbt.TypeString, Payload: "minecraft:full"},
+	}}
+}
+
+// buildRegion lays out the chunks sequentially after the header, each
+// compressed with the given compression type.
+func buildRegion(t *testing.T, chunks map[[2]int]Compression, timestamp time.Time) []byte {
+	data := make([]byte, headerSize)
+	for pos, c := range chunks {
+		buf := new(bytes.Buffer)
+
+		var w io.WriteCloser
+		switch c {
+		case CompressionGzip:
+			w = gzip.NewWriter(buf)
+		case CompressionZlib:
+			w = zlib.NewWriter(buf)
+		default:
+			w = nopWriteCloser{buf}
+		}
+
+		if err := nbt.NewEncoder(w).Encode(testChunk(pos[0], pos[1])); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		offset := len(data) / SectorSize
+		sectors := (buf.Len() + 5 + SectorSize - 1) / SectorSize
+
+		chunk := make([]byte, sectors*SectorSize)
+		binary.BigEndian.PutUint32(chunk, uint32(buf.Len()+1))
+		chunk[4] = byte(c)
+		copy(chunk[5:], buf.Bytes())
+		data = append(data, chunk...)
+
+		i := index(pos[0], pos[1])
+		binary.BigEndian.PutUint32(data[4*i:], uint32(offset<<8|sectors))
+		binary.BigEndian.PutUint32(data[SectorSize+4*i:], uint32(timestamp.Unix()))
+	}
+	return data
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestReadChunk(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	chunks := map[[2]int]Compression{
+		{0, 0}:   CompressionGzip,
+		{1, 0}:   CompressionZlib,
+		{31, 31}: CompressionNone,
+	}
+
+	f, err := New(bytes.NewReader(buildRegion(t, chunks, timestamp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos := f.Chunks()
+	if len(infos) != len(chunks) {
+		t.Fatalf("expected %d chunks, got %d", len(chunks), len(infos))
+	}
+
+	for _, info := range infos {
+		if _, ok := chunks[[2]int{info.X, info.Z}]; !ok {
+			t.Errorf("unexpected chunk (%d, %d)", info.X, info.Z)
+		}
+		if !info.Timestamp.Equal(timestamp) {
+			t.Errorf("chunk (%d, %d): expected timestamp %v, got %v", info.X, info.Z, timestamp, info.Timestamp)
+		}
+	}
+
+	for pos := range chunks {
+		// absolute coordinates in region (-1, -1)
+		tag, err := f.ReadChunk(pos[0]-32, pos[1]-32)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(testChunk(pos[0], pos[1]), tag); diff != "" {
+			t.Fatalf("chunk (%d, %d): cmp.Diff(expected, got):\n%v", pos[0], pos[1], diff)
+		}
+	}
+
+	if _, err := f.ReadChunk(5, 5); errors.Cause(err) != ErrNoChunk {
+		t.Fatalf("expected ErrNoChunk, got %v", err)
+	}
+}
+
+func TestEmptyRegion(t *testing.T) {
+	f, err := New(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chunks := f.Chunks(); len(chunks) != 0 {
+		t.Fatalf("expected no chunks, got %v", chunks)
+	}
+}
+
+// eofReaderAt returns io.EOF with the data that reaches the end of its input,
+// as io.ReaderAt allows.
+type eofReaderAt struct {
+	*bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	if err == nil && off+int64(n) == r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestHeaderAtEOF(t *testing.T) {
+	data := buildRegion(t, nil, time.Now())[:headerSize]
+	if _, err := New(eofReaderAt{bytes.NewReader(data)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(eofReaderAt{bytes.NewReader(data[:headerSize-1])}); err == nil {
+		t.Fatal("expected error for truncated header")
+	}
+}
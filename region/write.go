@@ -0,0 +1,240 @@
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/njhanley/nbt"
+	"github.com/pkg/errors"
+)
+
+// maxSectors is the largest number of sectors a chunk location can refer to.
+const maxSectors = 0xff
+
+// maxOffset is the largest sector offset a chunk location can refer to.
+const maxOffset = 0xffffff
+
+// Create creates or truncates the named region file and writes an empty
+// header.
+func Create(name string) (*File, error) {
+	f, err := OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.writeAt(make([]byte, headerSize), 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SetCompression sets the compression used by WriteChunk, which defaults to
+// zlib as used by Minecraft.
+func (f *File) SetCompression(c Compression) {
+	f.compression = c
+}
+
+// WriteChunk encodes tag as the data of chunk (x, z) and sets its timestamp
+// to the current time. The chunk is written in place if it still fits in its
+// sectors, and otherwise in the first free space large enough to hold it.
+// Chunks larger than 255 sectors are written to an external file as Minecraft
+// does, which requires the region file to be named r.X.Z.mca.
+func (f *File) WriteChunk(x, z int, tag *nbt.NamedTag) error {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 5))
+
+	var w io.WriteCloser
+	switch f.compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(buf)
+	case CompressionNone:
+		w = nopCloser{buf}
+	case CompressionLZ4:
+		w = newLZ4Writer(buf)
+	default:
+		return errors.Errorf("unknown compression (%v)", f.compression)
+	}
+
+	if err := nbt.NewEncoder(w).Encode(tag); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	data[4] = byte(f.compression)
+
+	if (len(data)+SectorSize-1)/SectorSize > maxSectors {
+		return f.writeExternal(x, z, data)
+	}
+
+	if err := f.writeChunkData(x, z, data); err != nil {
+		return err
+	}
+	return f.removeExternal(x, z)
+}
+
+// writeExternal writes the compressed data of chunk (x, z) to its external
+// file, leaving only the chunk header in the region file.
+func (f *File) writeExternal(x, z int, data []byte) error {
+	name, err := f.externalName(x, z)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(name, data[5:], 0666); err != nil {
+		return errors.WithStack(err)
+	}
+
+	header := data[:5]
+	binary.BigEndian.PutUint32(header, 1)
+	header[4] |= externalFlag
+
+	return f.writeChunkData(x, z, header)
+}
+
+// removeExternal removes the external file of chunk (x, z), if any.
+func (f *File) removeExternal(x, z int) error {
+	name, err := f.externalName(x, z)
+	if err != nil {
+		// there cannot be an external file
+		return nil
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// writeChunkData writes data, which begins with the chunk header, to the
+// sectors of chunk (x, z).
+func (f *File) writeChunkData(x, z int, data []byte) error {
+	sectors := (len(data) + SectorSize - 1) / SectorSize
+	if sectors > maxSectors {
+		return errors.Errorf("chunk too large (%d bytes)", len(data))
+	}
+
+	info := f.Chunk(x, z)
+	if info.Offset != 0 {
+		f.markUsed(info.Offset, info.Sectors, false)
+	}
+
+	offset := info.Offset
+	if offset == 0 || sectors > info.Sectors {
+		offset = f.allocate(sectors)
+	}
+
+	if offset > maxOffset {
+		if info.Offset != 0 {
+			f.markUsed(info.Offset, info.Sectors, true)
+		}
+		return errors.Errorf("region file full (sector offset %d)", offset)
+	}
+
+	padded := make([]byte, sectors*SectorSize)
+	copy(padded, data)
+	if err := f.writeAt(padded, int64(offset)*SectorSize); err != nil {
+		if info.Offset != 0 {
+			f.markUsed(info.Offset, info.Sectors, true)
+		}
+		return err
+	}
+	f.markUsed(offset, sectors, true)
+
+	return f.setLocation(index(x, z), uint32(offset<<8|sectors), uint32(time.Now().Unix()))
+}
+
+// DeleteChunk removes chunk (x, z) from the file, freeing its sectors and
+// removing its external file.
+func (f *File) DeleteChunk(x, z int) error {
+	info := f.Chunk(x, z)
+	if info.Offset == 0 {
+		return errors.WithStack(ErrNoChunk)
+	}
+
+	if err := f.setLocation(index(x, z), 0, 0); err != nil {
+		return err
+	}
+	f.markUsed(info.Offset, info.Sectors, false)
+
+	return f.removeExternal(x, z)
+}
+
+func (f *File) setLocation(i int, location, timestamp uint32) error {
+	var b [4]byte
+
+	binary.BigEndian.PutUint32(b[:], location)
+	if err := f.writeAt(b[:], int64(4*i)); err != nil {
+		return err
+	}
+	f.locations[i] = location
+
+	binary.BigEndian.PutUint32(b[:], timestamp)
+	if err := f.writeAt(b[:], int64(SectorSize+4*i)); err != nil {
+		return err
+	}
+	f.timestamps[i] = timestamp
+
+	return nil
+}
+
+func (f *File) writeAt(b []byte, off int64) error {
+	w, ok := f.r.(io.WriterAt)
+	if !ok {
+		return errors.New("region file is not writable")
+	}
+
+	_, err := w.WriteAt(b, off)
+	return errors.WithStack(err)
+}
+
+func (f *File) markUsed(offset, sectors int, used bool) {
+	end := offset + sectors
+	if used && end > len(f.used) {
+		f.used = append(f.used, make([]bool, end-len(f.used))...)
+	}
+
+	for i := offset; i < end && i < len(f.used); i++ {
+		f.used[i] = used
+	}
+}
+
+// allocate returns the offset of the first run of free sectors of the given
+// length, which may extend past the end of the file.
+func (f *File) allocate(sectors int) int {
+	var run int
+	for i, used := range f.used {
+		if used {
+			run = 0
+			continue
+		}
+
+		run++
+		if run == sectors {
+			return i - run + 1
+		}
+	}
+	return len(f.used) - run
+}
@@ -0,0 +1,189 @@
+package region
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/njhanley/nbt"
+	"github.com/pkg/errors"
+)
+
+func largeChunk(n int) *nbt.NamedTag {
+	return &nbt.NamedTag{Type: nbt.TypeCompound, Payload: nbt.Compound{
+		"data": &nbt.Tag{Type: nbt.TypeByteArray, Payload: make([]byte, n)},
+	}}
+}
+
+func TestWriteChunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "r.0.0.mca")
+
+	f, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetCompression(CompressionNone)
+
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(f.WriteChunk(0, 0, largeChunk(3*SectorSize)))
+	check(f.WriteChunk(1, 0, testChunk(1, 0)))
+
+	if info := f.Chunk(0, 0); info.Offset != 2 || info.Sectors != 4 {
+		t.Fatalf("chunk (0, 0): unexpected location %+v", info)
+	}
+	if info := f.Chunk(1, 0); info.Offset != 6 || info.Sectors != 1 {
+		t.Fatalf("chunk (1, 0): unexpected location %+v", info)
+	}
+
+	// shrinking in place, then filling the freed sectors
+	check(f.WriteChunk(0, 0, testChunk(0, 0)))
+	check(f.WriteChunk(2, 0, largeChunk(2*SectorSize)))
+	check(f.WriteChunk(3, 0, largeChunk(2*SectorSize)))
+
+	if info := f.Chunk(0, 0); info.Offset != 2 || info.Sectors != 1 {
+		t.Fatalf("chunk (0, 0): unexpected location %+v", info)
+	}
+	if info := f.Chunk(2, 0); info.Offset != 3 {
+		t.Fatalf("chunk (2, 0): unexpected location %+v", info)
+	}
+	if info := f.Chunk(3, 0); info.Offset != 7 {
+		t.Fatalf("chunk (3, 0): unexpected location %+v", info)
+	}
+
+	check(f.DeleteChunk(2, 0))
+	f.SetCompression(CompressionZlib)
+	check(f.WriteChunk(2, 0, testChunk(2, 0)))
+	check(f.Close())
+
+	f, err = Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if n := len(f.Chunks()); n != 4 {
+		t.Fatalf("expected 4 chunks, got %d", n)
+	}
+
+	for _, x := range []int{0, 1, 2} {
+		tag, err := f.ReadChunk(x, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(testChunk(x, 0), tag); diff != "" {
+			t.Fatalf("chunk (%d, 0): cmp.Diff(expected, got):\n%v", x, diff)
+		}
+	}
+
+	if err := f.WriteChunk(4, 0, testChunk(4, 0)); err == nil {
+		t.Fatal("expected error writing to read-only file")
+	}
+
+	if err := f.DeleteChunk(5, 0); errors.Cause(err) != ErrNoChunk {
+		t.Fatalf("expected ErrNoChunk, got %v", err)
+	}
+}
+
+func TestWriteChunkExternal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := Create(filepath.Join(dir, "r.-1.2.mca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.SetCompression(CompressionNone)
+
+	ext := filepath.Join(dir, "c.-31.64.mcc")
+	chunk := largeChunk(256 * SectorSize)
+
+	if err := f.WriteChunk(1, 0, chunk); err != nil {
+		t.Fatal(err)
+	}
+
+	if info := f.Chunk(1, 0); info.Sectors != 1 {
+		t.Fatalf("unexpected location %+v", info)
+	}
+	if _, err := os.Stat(ext); err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := f.ReadChunk(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(chunk, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	// compressed, the chunk fits in the region file
+	f.SetCompression(CompressionLZ4)
+	if err := f.WriteChunk(1, 0, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ext); !os.IsNotExist(err) {
+		t.Fatalf("expected external file to be removed, got %v", err)
+	}
+
+	tag, err = f.ReadChunk(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(chunk, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	g, err := Create(filepath.Join(dir, "region.mca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	g.SetCompression(CompressionNone)
+
+	if err := g.WriteChunk(0, 0, chunk); err == nil {
+		t.Fatal("expected error writing external chunk without region coordinates")
+	}
+}
+
+func TestWriteChunkOffsetOverflow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := Create(filepath.Join(dir, "r.0.0.mca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// pretend every sector addressable by a location is used
+	f.markUsed(0, maxOffset+1, true)
+
+	if err := f.WriteChunk(0, 0, largeChunk(SectorSize)); err == nil {
+		t.Fatal("expected error writing past the largest sector offset")
+	}
+	if info := f.Chunk(0, 0); info.Offset != 0 {
+		t.Fatalf("unexpected location %+v", info)
+	}
+}
@@ -0,0 +1,377 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Schema describes the expected shape of a tag. It is loaded from JSON such
+// as
+//
+//	{
+//		"type": "Compound",
+//		"required": ["Pos", "Health"],
+//		"tags": {
+//			"Pos": {"type": "List", "length": 3, "elements": {"type": "Double"}},
+//			"Health": {"type": "Float", "min": 0, "max": 20},
+//			"playerGameType": {"type": "Int", "enum": [0, 1, 2, 3]},
+//			"Dimension": {"type": "String", "enum": ["minecraft:overworld", "minecraft:the_nether"]}
+//		}
+//	}
+type Schema struct {
+	// Type of the tag. End matches any type.
+	Type Type `json:"type"`
+
+	// Tags are the schemas of the tags of a compound that are checked if
+	// present. Required tags must be present, and no other tags are allowed
+	// if Strict is set.
+	Tags     map[string]*Schema `json:"tags,omitempty"`
+	Required []string           `json:"required,omitempty"`
+	Strict   bool               `json:"strict,omitempty"`
+
+	// Elements is the schema of the elements of a list or array.
+	Elements *Schema `json:"elements,omitempty"`
+
+	// Length limits the length of a string in characters, or of a list or
+	// array. MinLength and MaxLength are ignored if it is set.
+	Length    *int `json:"length,omitempty"`
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+
+	// Min and Max limit the value of a number, compared as a float64.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+
+	// Enum lists the allowed values of a string or number.
+	Enum []interface{} `json:"enum,omitempty"`
+}
+
+// ParseSchema parses a schema from JSON, returning an error if it has unknown
+// fields or constraints that do not apply to its type.
+func ParseSchema(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+
+	schema := new(Schema)
+	if err := dec.Decode(schema); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := schema.check(""); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+var numberTypes = map[Type]bool{
+	TypeByte:   true,
+	TypeShort:  true,
+	TypeInt:    true,
+	TypeLong:   true,
+	TypeFloat:  true,
+	TypeDouble: true,
+}
+
+// check returns an error if a constraint of the schema at path does not apply
+// to its type.
+func (schema *Schema) check(path string) error {
+	if schema == nil {
+		return errors.Errorf("schema at %s: null schema", pathString(path))
+	}
+
+	typ := schema.Type
+	fail := func(field string) error {
+		return errors.Errorf("schema at %s: %s not allowed for type %v", pathString(path), field, typ)
+	}
+
+	_, array := arrayElemTypes[typ]
+	anyType := typ == TypeEnd
+	switch {
+	case (schema.Tags != nil || schema.Required != nil || schema.Strict) && typ != TypeCompound && !anyType:
+		return fail("tags")
+	case schema.Elements != nil && typ != TypeList && !array && !anyType:
+		return fail("elements")
+	case (schema.Length != nil || schema.MinLength != nil || schema.MaxLength != nil) && typ != TypeString && typ != TypeList && !array && !anyType:
+		return fail("length")
+	case (schema.Min != nil || schema.Max != nil) && !numberTypes[typ] && !anyType:
+		return fail("min and max")
+	case schema.Enum != nil && typ != TypeString && !numberTypes[typ] && !anyType:
+		return fail("enum")
+	}
+
+	for _, v := range schema.Enum {
+		if _, ok := v.(string); ok {
+			continue
+		}
+		if _, ok := enumNumber(v); !ok {
+			return errors.Errorf("schema at %s: enum value is not a string or number (%v)", pathString(path), v)
+		}
+	}
+
+	if schema.Elements != nil {
+		if elem := arrayElemTypes[typ]; array && schema.Elements.Type != elem && schema.Elements.Type != TypeEnd {
+			return errors.Errorf("schema at %s: array element type mismatch (%v, %v)", pathString(path), elem, schema.Elements.Type)
+		}
+		if err := schema.Elements.check(path + "[]"); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedNames(schema.Tags) {
+		if err := schema.Tags[name].check(childPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedNames(tags map[string]*Schema) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pathString(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// Violation is a tag that does not match a schema.
+type Violation struct {
+	// Path of the tag in the syntax of ParsePath.
+	Path string
+	Msg  string
+}
+
+func (v Violation) String() string {
+	return pathString(v.Path) + ": " + v.Msg
+}
+
+// ValidationError is returned by Validate with every violation found.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the payload of tag against schema, returning a
+// ValidationError with every violation in the order of their paths, or nil if
+// there are none.
+func Validate(tag *NamedTag, schema *Schema) error {
+	v := new(validator)
+	v.tag("", &Tag{tag.Type, tag.Payload}, schema)
+	if len(v.violations) > 0 {
+		return &ValidationError{v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(path, format string, a ...interface{}) {
+	v.violations = append(v.violations, Violation{path, fmt.Sprintf(format, a...)})
+}
+
+func (v *validator) tag(path string, tag *Tag, schema *Schema) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Type != TypeEnd && tag.Type != schema.Type {
+		v.add(path, "expected type %v, got %v", schema.Type, tag.Type)
+		return
+	}
+
+	payload := tag.Payload
+	if raw, ok := payload.(RawTag); ok {
+		var err error
+		if payload, err = raw.Decode(); err != nil {
+			v.add(path, "invalid raw tag: %v", err)
+			return
+		}
+	}
+
+	switch p := payload.(type) {
+	case string:
+		v.length(path, utf8.RuneCountInString(p), schema)
+		v.enum(path, p, schema)
+	case Compound, *OrderedCompound:
+		v.compound(path, p, schema)
+	case *List:
+		v.list(path, p, schema)
+	case []byte:
+		v.length(path, len(p), schema)
+		for i, e := range p {
+			v.elem(path, i, &Tag{TypeByte, int8(e)}, schema)
+		}
+	case []int32:
+		v.length(path, len(p), schema)
+		for i, e := range p {
+			v.elem(path, i, &Tag{TypeInt, e}, schema)
+		}
+	case []int64:
+		v.length(path, len(p), schema)
+		for i, e := range p {
+			v.elem(path, i, &Tag{TypeLong, e}, schema)
+		}
+	default:
+		if x, ok := toFloat64(p); ok {
+			if schema.Min != nil && x < *schema.Min {
+				v.add(path, "value %v less than minimum %v", p, *schema.Min)
+			}
+			if schema.Max != nil && x > *schema.Max {
+				v.add(path, "value %v greater than maximum %v", p, *schema.Max)
+			}
+			v.enum(path, p, schema)
+		}
+	}
+}
+
+func (v *validator) elem(path string, i int, tag *Tag, schema *Schema) {
+	if schema.Elements != nil {
+		v.tag(path+indexSegment(i), tag, schema.Elements)
+	}
+}
+
+func (v *validator) compound(path string, payload interface{}, schema *Schema) {
+	if _, ok := compoundLen(payload); !ok {
+		v.add(path, "invalid compound")
+		return
+	}
+
+	var names []string
+	eachTag(payload, func(name string, tag *Tag) bool {
+		names = append(names, name)
+		return true
+	})
+	for _, name := range schema.Required {
+		if getTag(payload, name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+
+		p := childPath(path, name)
+		tag := getTag(payload, name)
+		s, ok := schema.Tags[name]
+		switch {
+		case tag == nil:
+			v.add(p, "missing required tag")
+		case ok:
+			v.tag(p, tag, s)
+		case schema.Strict:
+			v.add(p, "unexpected tag")
+		}
+	}
+}
+
+func (v *validator) list(path string, l *List, schema *Schema) {
+	if l == nil || !validList(l) {
+		v.add(path, "invalid list")
+		return
+	}
+
+	n := l.Length()
+	v.length(path, n, schema)
+
+	if elem := schema.Elements; elem != nil && n > 0 && elem.Type != TypeEnd && l.Type != elem.Type {
+		v.add(path, "expected elements of type %v, got %v", elem.Type, l.Type)
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		v.elem(path, i, &Tag{l.Type, listElem(l, i)}, schema)
+	}
+}
+
+func (v *validator) length(path string, n int, schema *Schema) {
+	switch {
+	case schema.Length != nil:
+		if n != *schema.Length {
+			v.add(path, "expected length %d, got %d", *schema.Length, n)
+		}
+	case schema.MinLength != nil && n < *schema.MinLength:
+		v.add(path, "length %d less than minimum %d", n, *schema.MinLength)
+	case schema.MaxLength != nil && n > *schema.MaxLength:
+		v.add(path, "length %d greater than maximum %d", n, *schema.MaxLength)
+	}
+}
+
+func (v *validator) enum(path string, value interface{}, schema *Schema) {
+	if schema.Enum == nil {
+		return
+	}
+
+	s, isString := value.(string)
+	x, _ := toFloat64(value)
+	for _, e := range schema.Enum {
+		if t, ok := e.(string); ok {
+			if isString && t == s {
+				return
+			}
+		} else if y, ok := enumNumber(e); ok && !isString && x == y {
+			return
+		}
+	}
+
+	if isString {
+		v.add(path, "value %q not allowed", s)
+	} else {
+		v.add(path, "value %v not allowed", value)
+	}
+}
+
+// enumNumber returns a number of Schema.Enum as a float64.
+func enumNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		x, err := v.Float64()
+		return x, err == nil
+	case int:
+		return float64(v), true
+	}
+	return toFloat64(v)
+}
+
+func toFloat64(payload interface{}) (float64, bool) {
+	switch p := payload.(type) {
+	case int8:
+		return float64(p), true
+	case int16:
+		return float64(p), true
+	case int32:
+		return float64(p), true
+	case int64:
+		return float64(p), true
+	case float32:
+		return float64(p), true
+	case float64:
+		return p, true
+	}
+	return 0, false
+}
@@ -0,0 +1,106 @@
+package nbt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testSchema = `{
+	"type": "Compound",
+	"required": ["Data"],
+	"tags": {
+		"Data": {
+			"type": "Compound",
+			"required": ["Player", "Version"],
+			"tags": {
+				"Player": {
+					"type": "Compound",
+					"strict": true,
+					"tags": {
+						"Pos": {"type": "List", "length": 3, "elements": {"type": "Double", "min": -100, "max": 60}},
+						"Inventory": {"type": "List", "maxLength": 2, "elements": {
+							"type": "Compound",
+							"tags": {
+								"Slot": {"type": "Byte", "enum": [0, 1]},
+								"id": {"type": "String", "enum": ["minecraft:stone"]}
+							}
+						}},
+						"UUID": {"type": "IntArray", "elements": {"type": "Int", "max": 3}},
+						"a.b": {"type": "Int"},
+						"minecraft:id": {}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Validate(testPlayer(), schema)
+	e, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	expected := []Violation{
+		{"Data.Player.Inventory", "length 3 greater than maximum 2"},
+		{"Data.Player.Inventory[1].id", `value "minecraft:dirt" not allowed`},
+		{"Data.Player.Inventory[2].Slot", "value 2 not allowed"},
+		{"Data.Player.Pos[1]", "value 64 greater than maximum 60"},
+		{"Data.Player.UUID[3]", "value 4 greater than maximum 3"},
+		{`Data.Player."a.b"`, "expected type Int, got String"},
+		{"Data.Version", "missing required tag"},
+	}
+	if diff := cmp.Diff(expected, e.Violations); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type": "Compound", "strict": true, "tags": {"a": {"type": "List", "elements": {"type": "Int"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := &NamedTag{TypeCompound, "", Compound{
+		"a": &Tag{TypeList, &List{TypeString, []string{"x"}}},
+		"b": &Tag{TypeByte, int8(1)},
+	}}
+
+	expected := "a: expected elements of type Int, got String; b: unexpected tag"
+	if err := Validate(tag, schema); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+
+	tag.Payload = Compound{"a": &Tag{TypeList, &List{TypeEnd, nil}}}
+	if err := Validate(tag, schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := []string{
+		`{"type": "Int", "tags": {}}`,
+		`{"type": "Compound", "elements": {"type": "Int"}}`,
+		`{"type": "Byte", "length": 1}`,
+		`{"type": "String", "min": 0}`,
+		`{"type": "List", "enum": [1]}`,
+		`{"type": "IntArray", "elements": {"type": "Long"}}`,
+		`{"type": "String", "enum": [true]}`,
+		`{"type": "Compound", "tags": {"a": null}}`,
+		`{"type": "Compound", "unknown": 1}`,
+		`{"type": "Unknown"}`,
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSchema([]byte(test)); err == nil {
+			t.Errorf("%s: expected error", test)
+		}
+	}
+}
@@ -0,0 +1,703 @@
+package nbt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// SNBT is the stringified form of NBT used by Minecraft commands, such as
+// {Count:1b,id:"minecraft:stone",tag:{Damage:0}}. It has no notion of a root
+// name, so a NamedTag is parsed and formatted through its type and payload.
+
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
+func (e *SyntaxError) Format(f fmt.State, c rune) {
+	if f.Flag('+') {
+		fmt.Fprintf(f, "offset %d: %s", e.Offset, e.Msg)
+	} else {
+		fmt.Fprint(f, e.Msg)
+	}
+}
+
+// ParseSNBT parses a single SNBT value.
+func ParseSNBT(s string) (*Tag, error) {
+	p := &snbtParser{s: s}
+
+	tag, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return nil, p.errorf("unexpected trailing data (%q)", p.s[p.i:])
+	}
+
+	return tag, nil
+}
+
+// FormatSNBT formats a payload of the given type as compact SNBT. NaN and
+// infinite floats cannot be formatted, as SNBT has no syntax for them.
+func FormatSNBT(typ Type, payload interface{}) (string, error) {
+	return FormatSNBTIndent(typ, payload, "", "")
+}
+
+// FormatSNBTIndent is like FormatSNBT but puts each element of a compound or
+// list on a new line beginning with prefix followed by one or more copies of
+// indent according to the nesting depth.
+func FormatSNBTIndent(typ Type, payload interface{}, prefix, indent string) (string, error) {
+	f := &snbtFormatter{prefix: prefix, indent: indent}
+	if err := f.formatPayload(typ, payload); err != nil {
+		return "", err
+	}
+	return f.b.String(), nil
+}
+
+var (
+	snbtInteger  = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)([bBsSlL]?)$`)
+	snbtFloat    = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?([fFdD])$`)
+	snbtDoubleNS = regexp.MustCompile(`^[-+]?(?:[0-9]+\.|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?$`)
+)
+
+func isUnquotedChar(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' ||
+		c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+type snbtParser struct {
+	s string
+	i int
+}
+
+func (p *snbtParser) errorf(format string, a ...interface{}) error {
+	return errors.WithStack(&SyntaxError{p.i, fmt.Sprintf(format, a...)})
+}
+
+func (p *snbtParser) skipSpace() {
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ' ', '\t', '\n', '\r':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end of the input.
+func (p *snbtParser) peek() byte {
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *snbtParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.unexpected(fmt.Sprintf("%q", c))
+	}
+	p.i++
+	return nil
+}
+
+func (p *snbtParser) unexpected(expected string) error {
+	if p.i >= len(p.s) {
+		return p.errorf("unexpected end of input, expected %s", expected)
+	}
+	r, _ := utf8.DecodeRuneInString(p.s[p.i:])
+	return p.errorf("unexpected character (%q), expected %s", r, expected)
+}
+
+func (p *snbtParser) parseValue() (*Tag, error) {
+	switch p.peek() {
+	case '{':
+		m, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		return &Tag{TypeCompound, m}, nil
+	case '[':
+		if p.i+2 < len(p.s) && p.s[p.i+2] == ';' {
+			return p.parseArray()
+		}
+		l, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return &Tag{TypeList, l}, nil
+	case '"', '\'':
+		s, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return &Tag{TypeString, s}, nil
+	}
+
+	start := p.i
+	for p.i < len(p.s) && isUnquotedChar(p.s[p.i]) {
+		p.i++
+	}
+	if p.i == start {
+		return nil, p.unexpected("value")
+	}
+
+	return parseUnquoted(p.s[start:p.i]), nil
+}
+
+// parseUnquoted returns the tag for an unquoted value, which is a string
+// unless it looks like a number that fits in the type given by its suffix.
+func parseUnquoted(s string) *Tag {
+	if m := snbtInteger.FindStringSubmatch(s); m != nil {
+		digits := s[:len(s)-len(m[1])]
+		switch m[1] {
+		case "b", "B":
+			if n, err := strconv.ParseInt(digits, 10, 8); err == nil {
+				return &Tag{TypeByte, int8(n)}
+			}
+		case "s", "S":
+			if n, err := strconv.ParseInt(digits, 10, 16); err == nil {
+				return &Tag{TypeShort, int16(n)}
+			}
+		case "l", "L":
+			if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+				return &Tag{TypeLong, n}
+			}
+		default:
+			if n, err := strconv.ParseInt(digits, 10, 32); err == nil {
+				return &Tag{TypeInt, int32(n)}
+			}
+		}
+	} else if m := snbtFloat.FindStringSubmatch(s); m != nil {
+		digits := s[:len(s)-1]
+		switch m[1] {
+		case "f", "F":
+			if x, err := strconv.ParseFloat(digits, 32); err == nil {
+				return &Tag{TypeFloat, float32(x)}
+			}
+		default:
+			if x, err := strconv.ParseFloat(digits, 64); err == nil {
+				return &Tag{TypeDouble, x}
+			}
+		}
+	} else if snbtDoubleNS.MatchString(s) {
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			return &Tag{TypeDouble, x}
+		}
+	} else if strings.EqualFold(s, "true") {
+		return &Tag{TypeByte, int8(1)}
+	} else if strings.EqualFold(s, "false") {
+		return &Tag{TypeByte, int8(0)}
+	}
+
+	return &Tag{TypeString, s}
+}
+
+func (p *snbtParser) parseQuoted() (string, error) {
+	quote := p.s[p.i]
+	p.i++
+
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch c {
+		case quote:
+			p.i++
+			return b.String(), nil
+		case '\\':
+			p.i++
+			if p.i >= len(p.s) {
+				return "", p.unexpected("escape sequence")
+			}
+
+			e := p.s[p.i]
+			p.i++
+			switch e {
+			case '\\', '"', '\'':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 's':
+				b.WriteByte(' ')
+			case 't':
+				b.WriteByte('\t')
+			case 'x', 'u', 'U':
+				n := 2
+				if e == 'u' {
+					n = 4
+				} else if e == 'U' {
+					n = 8
+				}
+
+				if p.i+n > len(p.s) {
+					return "", p.errorf("invalid escape sequence (%q)", p.s[p.i-2:])
+				}
+
+				r, err := strconv.ParseUint(p.s[p.i:p.i+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", p.errorf("invalid escape sequence (%q)", p.s[p.i-2:p.i+n])
+				}
+
+				b.WriteRune(rune(r))
+				p.i += n
+			default:
+				p.i--
+				return "", p.errorf("invalid escape sequence (%q)", p.s[p.i-1:p.i+1])
+			}
+		default:
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+
+	return "", p.unexpected(fmt.Sprintf("%q", quote))
+}
+
+func (p *snbtParser) parseKey() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.parseQuoted()
+	}
+
+	start := p.i
+	for p.i < len(p.s) && isUnquotedChar(p.s[p.i]) {
+		p.i++
+	}
+	if p.i == start {
+		return "", p.unexpected("key")
+	}
+
+	return p.s[start:p.i], nil
+}
+
+func (p *snbtParser) parseCompound() (Compound, error) {
+	p.i++
+
+	m := make(Compound)
+	if p.peek() == '}' {
+		p.i++
+		return m, nil
+	}
+
+	for {
+		name, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+
+		tag, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		m[name] = tag
+
+		switch p.peek() {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return m, nil
+		default:
+			return nil, p.unexpected(`',' or '}'`)
+		}
+	}
+}
+
+func (p *snbtParser) parseList() (*List, error) {
+	p.i++
+
+	if p.peek() == ']' {
+		p.i++
+		return &List{}, nil
+	}
+
+	var (
+		typ   Type
+		array reflect.Value
+	)
+	for {
+		start := p.i
+		tag, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if !array.IsValid() {
+			typ = tag.Type
+			array = reflect.MakeSlice(reflect.SliceOf(payloadTypes[typ]), 0, 1)
+		} else if tag.Type != typ {
+			p.i = start
+			return nil, p.errorf("mixed list element types (%v, %v)", typ, tag.Type)
+		}
+		array = reflect.Append(array, reflect.ValueOf(tag.Payload))
+
+		switch p.peek() {
+		case ',':
+			p.i++
+		case ']':
+			p.i++
+			return &List{typ, array.Interface()}, nil
+		default:
+			return nil, p.unexpected(`',' or ']'`)
+		}
+	}
+}
+
+func (p *snbtParser) parseArray() (*Tag, error) {
+	var typ Type
+	switch p.s[p.i+1] {
+	case 'B':
+		typ = TypeByteArray
+	case 'I':
+		typ = TypeIntArray
+	case 'L':
+		typ = TypeLongArray
+	default:
+		p.i++
+		return nil, p.unexpected("array type")
+	}
+	p.i += 3
+
+	var (
+		b []byte
+		i []int32
+		l []int64
+	)
+	if p.peek() != ']' {
+		for {
+			start := p.i
+			tag, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+
+			var n int64
+			switch tag.Type {
+			case TypeByte, TypeShort, TypeInt, TypeLong:
+				n = reflect.ValueOf(tag.Payload).Int()
+			default:
+				p.i = start
+				return nil, p.errorf("invalid %v element (%v)", typ, tag.Type)
+			}
+
+			switch {
+			case typ == TypeByteArray && n >= math.MinInt8 && n <= math.MaxInt8:
+				b = append(b, byte(n))
+			case typ == TypeIntArray && n >= math.MinInt32 && n <= math.MaxInt32:
+				i = append(i, int32(n))
+			case typ == TypeLongArray:
+				l = append(l, n)
+			default:
+				p.i = start
+				return nil, p.errorf("%v element out of range (%d)", typ, n)
+			}
+
+			if p.peek() != ',' {
+				break
+			}
+			p.i++
+		}
+	}
+
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case TypeByteArray:
+		if b == nil {
+			b = []byte{}
+		}
+		return &Tag{typ, b}, nil
+	case TypeIntArray:
+		if i == nil {
+			i = []int32{}
+		}
+		return &Tag{typ, i}, nil
+	default:
+		if l == nil {
+			l = []int64{}
+		}
+		return &Tag{typ, l}, nil
+	}
+}
+
+type snbtFormatter struct {
+	b      strings.Builder
+	prefix string
+	indent string
+	depth  int
+}
+
+func (f *snbtFormatter) pretty() bool {
+	return f.prefix != "" || f.indent != ""
+}
+
+func (f *snbtFormatter) newline() {
+	if f.pretty() {
+		f.b.WriteByte('\n')
+		f.b.WriteString(f.prefix)
+		for i := 0; i < f.depth; i++ {
+			f.b.WriteString(f.indent)
+		}
+	}
+}
+
+func (f *snbtFormatter) separator() {
+	f.b.WriteByte(',')
+	if f.pretty() {
+		f.b.WriteByte(' ')
+	}
+}
+
+func (f *snbtFormatter) formatPayload(typ Type, payload interface{}) error {
+	if raw, isRaw := payload.(RawTag); isRaw {
+		var err error
+		if payload, err = raw.Decode(); err != nil {
+			return err
+		}
+	}
+
+	ok := true
+	switch typ {
+	case TypeByte:
+		var n int8
+		n, ok = payload.(int8)
+		f.b.WriteString(strconv.FormatInt(int64(n), 10) + "b")
+	case TypeShort:
+		var n int16
+		n, ok = payload.(int16)
+		f.b.WriteString(strconv.FormatInt(int64(n), 10) + "s")
+	case TypeInt:
+		var n int32
+		n, ok = payload.(int32)
+		f.b.WriteString(strconv.FormatInt(int64(n), 10))
+	case TypeLong:
+		var n int64
+		n, ok = payload.(int64)
+		f.b.WriteString(strconv.FormatInt(n, 10) + "L")
+	case TypeFloat:
+		var x float32
+		x, ok = payload.(float32)
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return errors.Errorf("SNBT cannot represent %v (%v)", x, typ)
+		}
+		f.b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32) + "f")
+	case TypeDouble:
+		var x float64
+		x, ok = payload.(float64)
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return errors.Errorf("SNBT cannot represent %v (%v)", x, typ)
+		}
+		f.b.WriteString(strconv.FormatFloat(x, 'g', -1, 64) + "d")
+	case TypeString:
+		var s string
+		s, ok = payload.(string)
+		f.b.WriteString(quoteSNBT(s))
+	case TypeByteArray, TypeIntArray, TypeLongArray:
+		ok = f.formatArray(typ, payload)
+	case TypeList:
+		var l *List
+		if l, ok = payload.(*List); ok {
+			return f.formatList(l)
+		}
+	case TypeCompound:
+		if c, isOrdered := payload.(*OrderedCompound); isOrdered {
+			return f.formatTags(c.Names(), c.Get)
+		}
+
+		var m Compound
+		if m, ok = payload.(Compound); ok {
+			return f.formatCompound(m)
+		}
+	default:
+		return errors.Errorf("unknown type (%v)", typ)
+	}
+
+	if !ok {
+		return errors.Errorf("invalid payload for type %v (%T)", typ, payload)
+	}
+
+	return nil
+}
+
+func (f *snbtFormatter) formatArray(typ Type, payload interface{}) bool {
+	if reflect.TypeOf(payload) != payloadTypes[typ] {
+		return false
+	}
+
+	var (
+		prefix, suffix string
+		a              []int64
+	)
+	switch v := payload.(type) {
+	case []byte:
+		prefix, suffix = "[B;", "b"
+		a = make([]int64, len(v))
+		for i, n := range v {
+			a[i] = int64(int8(n))
+		}
+	case []int32:
+		prefix = "[I;"
+		a = make([]int64, len(v))
+		for i, n := range v {
+			a[i] = int64(n)
+		}
+	case []int64:
+		prefix, suffix = "[L;", "L"
+		a = v
+	}
+
+	f.b.WriteString(prefix)
+	for i, n := range a {
+		if i > 0 {
+			f.separator()
+		} else if f.pretty() {
+			f.b.WriteByte(' ')
+		}
+		f.b.WriteString(strconv.FormatInt(n, 10) + suffix)
+	}
+	f.b.WriteByte(']')
+
+	return true
+}
+
+func (f *snbtFormatter) formatList(l *List) error {
+	length := l.Length()
+	if length == 0 {
+		f.b.WriteString("[]")
+		return nil
+	}
+
+	t := reflect.TypeOf(l.Array)
+	if l.Type < TypeByte || l.Type > TypeLongArray ||
+		t != reflect.SliceOf(payloadTypes[l.Type]) && !(l.Type == TypeCompound && t == reflect.SliceOf(reflect.PtrTo(orderedCompoundType))) {
+		return errors.Errorf("invalid list array for type %v (%T)", l.Type, l.Array)
+	}
+
+	array := reflect.ValueOf(l.Array)
+
+	f.b.WriteByte('[')
+	f.depth++
+	for i := 0; i < length; i++ {
+		if i > 0 {
+			f.b.WriteByte(',')
+		}
+		f.newline()
+		if err := f.formatPayload(l.Type, array.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	f.depth--
+	f.newline()
+	f.b.WriteByte(']')
+
+	return nil
+}
+
+func (f *snbtFormatter) formatCompound(m Compound) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return f.formatTags(names, func(name string) *Tag { return m[name] })
+}
+
+// formatTags formats the tags of a compound in the order of names.
+func (f *snbtFormatter) formatTags(names []string, get func(string) *Tag) error {
+	if len(names) == 0 {
+		f.b.WriteString("{}")
+		return nil
+	}
+
+	f.b.WriteByte('{')
+	f.depth++
+	for i, name := range names {
+		if i > 0 {
+			f.b.WriteByte(',')
+		}
+		f.newline()
+
+		if isUnquotedKey(name) {
+			f.b.WriteString(name)
+		} else {
+			f.b.WriteString(quoteSNBT(name))
+		}
+
+		f.b.WriteByte(':')
+		if f.pretty() {
+			f.b.WriteByte(' ')
+		}
+
+		tag := get(name)
+		if err := f.formatPayload(tag.Type, tag.Payload); err != nil {
+			return err
+		}
+	}
+	f.depth--
+	f.newline()
+	f.b.WriteByte('}')
+
+	return nil
+}
+
+func isUnquotedKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isUnquotedChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteSNBT quotes s with double quotes, or with single quotes if that
+// avoids escaping, as Minecraft does.
+func quoteSNBT(s string) string {
+	quote := byte('"')
+	if strings.IndexByte(s, '"') >= 0 && strings.IndexByte(s, '\'') < 0 {
+		quote = '\''
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte(quote)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == quote || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(quote)
+
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package nbt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestSNBT(t *testing.T) {
+	s, err := FormatSNBT(testTag.Type, testTag.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := ParseSNBT(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&Tag{testTag.Type, testTag.Payload}, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestSNBTIndent(t *testing.T) {
+	s, err := FormatSNBTIndent(testTag.Type, testTag.Payload, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := ParseSNBT(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&Tag{testTag.Type, testTag.Payload}, tag); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestFormatSNBT(t *testing.T) {
+	m := Compound{
+		"Count": &Tag{TypeByte, int8(1)},
+		"id":    &Tag{TypeString, "minecraft:stone"},
+		"tag": &Tag{TypeCompound, Compound{
+			"Lore":       &Tag{TypeList, &List{TypeString, []string{`"quoted"`, `it's`}}},
+			"Pos":        &Tag{TypeList, &List{TypeDouble, []float64{0.5, 64, -0.5}}},
+			"UUID":       &Tag{TypeIntArray, []int32{1, -2}},
+			"key name":   &Tag{TypeLong, int64(math.MaxInt64)},
+			"Empty":      &Tag{TypeList, &List{}},
+			"Properties": &Tag{TypeCompound, Compound{}},
+			"Yaw":        &Tag{TypeFloat, float32(1.5)},
+			"Damage":     &Tag{TypeShort, int16(-1)},
+			"Bytes":      &Tag{TypeByteArray, []byte{0, 255}},
+		}},
+	}
+
+	s, err := FormatSNBT(TypeCompound, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{Count:1b,id:"minecraft:stone",tag:{Bytes:[B;0b,-1b],Damage:-1s,Empty:[],Lore:['"quoted"',"it's"],Pos:[0.5d,64d,-0.5d],Properties:{},UUID:[I;1,-2],Yaw:1.5f,"key name":9223372036854775807L}}`
+	if diff := cmp.Diff(expected, s); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	s, err = FormatSNBTIndent(TypeCompound, Compound{
+		"a": &Tag{TypeList, &List{TypeInt, []int32{1, 2}}},
+		"b": &Tag{TypeLongArray, []int64{1, 2}},
+		"c": &Tag{TypeCompound, Compound{"d": &Tag{TypeString, "e"}}},
+	}, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "{\n\ta: [\n\t\t1,\n\t\t2\n\t],\n\tb: [L; 1L, 2L],\n\tc: {\n\t\td: \"e\"\n\t}\n}"
+	if diff := cmp.Diff(expected, s); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+
+	// SNBT has no syntax for these, and NaNd or +Infd would be read back as
+	// strings
+	nonFinite := []*Tag{
+		{TypeDouble, math.NaN()},
+		{TypeDouble, math.Inf(1)},
+		{TypeFloat, float32(math.Inf(-1))},
+		{TypeList, &List{TypeFloat, []float32{0, float32(math.NaN())}}},
+	}
+	for _, tag := range nonFinite {
+		if s, err := FormatSNBT(tag.Type, tag.Payload); err == nil {
+			t.Errorf("%v: expected error, got %s", tag.Payload, s)
+		}
+	}
+}
+
+func TestParseSNBT(t *testing.T) {
+	tests := []struct {
+		s   string
+		tag *Tag
+	}{
+		{"1b", &Tag{TypeByte, int8(1)}},
+		{"true", &Tag{TypeByte, int8(1)}},
+		{"-32768s", &Tag{TypeShort, int16(math.MinInt16)}},
+		{"+7", &Tag{TypeInt, int32(7)}},
+		{"2147483648", &Tag{TypeString, "2147483648"}},
+		{"2147483648L", &Tag{TypeLong, int64(2147483648)}},
+		{"1.5", &Tag{TypeDouble, 1.5}},
+		{"1e3f", &Tag{TypeFloat, float32(1000)}},
+		{".5D", &Tag{TypeDouble, 0.5}},
+		{"1e3", &Tag{TypeString, "1e3"}},
+		{"minecraft:stone", nil},
+		{"minecraft.stone", &Tag{TypeString, "minecraft.stone"}},
+		{`'a\'b"c\\'`, &Tag{TypeString, `a'b"c\`}},
+		{`"é\x41\n"`, &Tag{TypeString, "éA\n"}},
+		{"[ ]", &Tag{TypeList, &List{}}},
+		{"[B;]", &Tag{TypeByteArray, []byte{}}},
+		{"[B; 1b, -1b]", &Tag{TypeByteArray, []byte{1, 255}}},
+		{"[L;1L,2]", &Tag{TypeLongArray, []int64{1, 2}}},
+		{"[I;1,2147483648L]", nil},
+		{"[1,2b]", nil},
+		{"{a:1,}", nil},
+		{"{a:1} x", nil},
+		{`{ "a b" : { } , c:[{}] }`, &Tag{TypeCompound, Compound{
+			"a b": &Tag{TypeCompound, Compound{}},
+			"c":   &Tag{TypeList, &List{TypeCompound, []Compound{{}}}},
+		}}},
+	}
+
+	for _, test := range tests {
+		tag, err := ParseSNBT(test.s)
+		if test.tag == nil {
+			if _, ok := errors.Cause(err).(*SyntaxError); !ok {
+				t.Errorf("ParseSNBT(%q): expected *SyntaxError, got %v, %#v", test.s, tag, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseSNBT(%q): %v", test.s, err)
+			continue
+		}
+
+		if diff := cmp.Diff(test.tag, tag); diff != "" {
+			t.Errorf("ParseSNBT(%q): cmp.Diff(expected, got):\n%v", test.s, diff)
+		}
+	}
+}
@@ -251,6 +251,104 @@ var testData = []byte{
 	0x6c, 0x6f, 0x2c, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x21, 0x00,
 }
 
+var testDataLE = []byte{
+	0x0a, 0x04, 0x00, 0x72, 0x6f, 0x6f, 0x74, 0x07, 0x09, 0x00, 0x62, 0x79, 0x74, 0x65, 0x41, 0x72,
+	0x72, 0x61, 0x79, 0x36, 0x00, 0x00, 0x00, 0x02, 0x03, 0x05, 0x07, 0x0b, 0x0d, 0x11, 0x13, 0x17,
+	0x1d, 0x1f, 0x25, 0x29, 0x2b, 0x2f, 0x35, 0x3b, 0x3d, 0x43, 0x47, 0x49, 0x4f, 0x53, 0x59, 0x61,
+	0x65, 0x67, 0x6b, 0x6d, 0x71, 0x7f, 0x83, 0x89, 0x8b, 0x95, 0x97, 0x9d, 0xa3, 0xa7, 0xad, 0xb3,
+	0xb5, 0xbf, 0xc1, 0xc5, 0xc7, 0xd3, 0xdf, 0xe3, 0xe5, 0xe9, 0xef, 0xf1, 0xfb, 0x01, 0x07, 0x00,
+	0x62, 0x79, 0x74, 0x65, 0x4d, 0x61, 0x78, 0x7f, 0x01, 0x07, 0x00, 0x62, 0x79, 0x74, 0x65, 0x4d,
+	0x69, 0x6e, 0x80, 0x0a, 0x0d, 0x00, 0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x75, 0x6e, 0x64, 0x45, 0x6d,
+	0x70, 0x74, 0x79, 0x00, 0x06, 0x09, 0x00, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x78,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xef, 0x7f, 0x06, 0x15, 0x00, 0x64, 0x6f, 0x75, 0x62, 0x6c,
+	0x65, 0x53, 0x6d, 0x61, 0x6c, 0x6c, 0x65, 0x73, 0x74, 0x4e, 0x6f, 0x6e, 0x7a, 0x65, 0x72, 0x6f,
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x08, 0x00, 0x66, 0x6c, 0x6f, 0x61, 0x74,
+	0x4d, 0x61, 0x78, 0xff, 0xff, 0x7f, 0x7f, 0x05, 0x14, 0x00, 0x66, 0x6c, 0x6f, 0x61, 0x74, 0x53,
+	0x6d, 0x61, 0x6c, 0x6c, 0x65, 0x73, 0x74, 0x4e, 0x6f, 0x6e, 0x7a, 0x65, 0x72, 0x6f, 0x01, 0x00,
+	0x00, 0x00, 0x0b, 0x08, 0x00, 0x69, 0x6e, 0x74, 0x41, 0x72, 0x72, 0x61, 0x79, 0x02, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x80, 0xff, 0xff, 0xff, 0x7f, 0x03, 0x06, 0x00, 0x69, 0x6e, 0x74, 0x4d,
+	0x61, 0x78, 0xff, 0xff, 0xff, 0x7f, 0x03, 0x06, 0x00, 0x69, 0x6e, 0x74, 0x4d, 0x69, 0x6e, 0x00,
+	0x00, 0x00, 0x80, 0x09, 0x08, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x74, 0x65, 0x01, 0x02,
+	0x00, 0x00, 0x00, 0x80, 0x7f, 0x09, 0x0d, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x74, 0x65,
+	0x41, 0x72, 0x72, 0x61, 0x79, 0x07, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01,
+	0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x09, 0x0c, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x43, 0x6f, 0x6d,
+	0x70, 0x6f, 0x75, 0x6e, 0x64, 0x0a, 0x02, 0x00, 0x00, 0x00, 0x08, 0x03, 0x00, 0x66, 0x6f, 0x6f,
+	0x03, 0x00, 0x66, 0x6f, 0x6f, 0x00, 0x08, 0x03, 0x00, 0x62, 0x61, 0x72, 0x03, 0x00, 0x62, 0x61,
+	0x72, 0x00, 0x09, 0x0a, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x06,
+	0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xef, 0x7f, 0x09, 0x09, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x09, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x46, 0x6c, 0x6f, 0x61,
+	0x74, 0x05, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xff, 0xff, 0x7f, 0x7f, 0x09, 0x07,
+	0x00, 0x6c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x03, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x80, 0xff, 0xff, 0xff, 0x7f, 0x09, 0x0c, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x41,
+	0x72, 0x72, 0x61, 0x79, 0x0b, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x09, 0x08, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x4c, 0x69, 0x73, 0x74, 0x09, 0x02, 0x00, 0x00,
+	0x00, 0x08, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x7a, 0x65, 0x72, 0x6f, 0x08, 0x01, 0x00, 0x00,
+	0x00, 0x03, 0x00, 0x6f, 0x6e, 0x65, 0x09, 0x08, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x4c, 0x6f, 0x6e,
+	0x67, 0x04, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x09, 0x0d, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x4c, 0x6f, 0x6e,
+	0x67, 0x41, 0x72, 0x72, 0x61, 0x79, 0x0c, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x09, 0x09, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x53, 0x68, 0x6f, 0x72, 0x74, 0x02,
+	0x02, 0x00, 0x00, 0x00, 0x00, 0x80, 0xff, 0x7f, 0x09, 0x0a, 0x00, 0x6c, 0x69, 0x73, 0x74, 0x53,
+	0x74, 0x72, 0x69, 0x6e, 0x67, 0x08, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x66, 0x6f, 0x6f, 0x03,
+	0x00, 0x62, 0x61, 0x72, 0x0c, 0x09, 0x00, 0x6c, 0x6f, 0x6e, 0x67, 0x41, 0x72, 0x72, 0x61, 0x79,
+	0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0x7f, 0x04, 0x07, 0x00, 0x6c, 0x6f, 0x6e, 0x67, 0x4d, 0x61, 0x78, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x04, 0x07, 0x00, 0x6c, 0x6f, 0x6e, 0x67, 0x4d, 0x69, 0x6e,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0x02, 0x08, 0x00, 0x73, 0x68, 0x6f, 0x72, 0x74,
+	0x4d, 0x61, 0x78, 0xff, 0x7f, 0x02, 0x08, 0x00, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x4d, 0x69, 0x6e,
+	0x00, 0x80, 0x08, 0x06, 0x00, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x0d, 0x00, 0x48, 0x65, 0x6c,
+	0x6c, 0x6f, 0x2c, 0x20, 0x77, 0x6f, 0x72, 0x6c, 0x64, 0x21, 0x00,
+}
+
+var testDataNetwork = []byte{
+	0x0a, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x07, 0x09, 0x62, 0x79, 0x74, 0x65, 0x41, 0x72, 0x72, 0x61,
+	0x79, 0x6c, 0x02, 0x03, 0x05, 0x07, 0x0b, 0x0d, 0x11, 0x13, 0x17, 0x1d, 0x1f, 0x25, 0x29, 0x2b,
+	0x2f, 0x35, 0x3b, 0x3d, 0x43, 0x47, 0x49, 0x4f, 0x53, 0x59, 0x61, 0x65, 0x67, 0x6b, 0x6d, 0x71,
+	0x7f, 0x83, 0x89, 0x8b, 0x95, 0x97, 0x9d, 0xa3, 0xa7, 0xad, 0xb3, 0xb5, 0xbf, 0xc1, 0xc5, 0xc7,
+	0xd3, 0xdf, 0xe3, 0xe5, 0xe9, 0xef, 0xf1, 0xfb, 0x01, 0x07, 0x62, 0x79, 0x74, 0x65, 0x4d, 0x61,
+	0x78, 0x7f, 0x01, 0x07, 0x62, 0x79, 0x74, 0x65, 0x4d, 0x69, 0x6e, 0x80, 0x0a, 0x0d, 0x63, 0x6f,
+	0x6d, 0x70, 0x6f, 0x75, 0x6e, 0x64, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x00, 0x06, 0x09, 0x64, 0x6f,
+	0x75, 0x62, 0x6c, 0x65, 0x4d, 0x61, 0x78, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xef, 0x7f, 0x06,
+	0x15, 0x64, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x53, 0x6d, 0x61, 0x6c, 0x6c, 0x65, 0x73, 0x74, 0x4e,
+	0x6f, 0x6e, 0x7a, 0x65, 0x72, 0x6f, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x08,
+	0x66, 0x6c, 0x6f, 0x61, 0x74, 0x4d, 0x61, 0x78, 0xff, 0xff, 0x7f, 0x7f, 0x05, 0x14, 0x66, 0x6c,
+	0x6f, 0x61, 0x74, 0x53, 0x6d, 0x61, 0x6c, 0x6c, 0x65, 0x73, 0x74, 0x4e, 0x6f, 0x6e, 0x7a, 0x65,
+	0x72, 0x6f, 0x01, 0x00, 0x00, 0x00, 0x0b, 0x08, 0x69, 0x6e, 0x74, 0x41, 0x72, 0x72, 0x61, 0x79,
+	0x04, 0xff, 0xff, 0xff, 0xff, 0x0f, 0xfe, 0xff, 0xff, 0xff, 0x0f, 0x03, 0x06, 0x69, 0x6e, 0x74,
+	0x4d, 0x61, 0x78, 0xfe, 0xff, 0xff, 0xff, 0x0f, 0x03, 0x06, 0x69, 0x6e, 0x74, 0x4d, 0x69, 0x6e,
+	0xff, 0xff, 0xff, 0xff, 0x0f, 0x09, 0x08, 0x6c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x74, 0x65, 0x01,
+	0x04, 0x80, 0x7f, 0x09, 0x0d, 0x6c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x74, 0x65, 0x41, 0x72, 0x72,
+	0x61, 0x79, 0x07, 0x04, 0x04, 0x00, 0x01, 0x04, 0x01, 0x00, 0x09, 0x0c, 0x6c, 0x69, 0x73, 0x74,
+	0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x75, 0x6e, 0x64, 0x0a, 0x04, 0x08, 0x03, 0x66, 0x6f, 0x6f, 0x03,
+	0x66, 0x6f, 0x6f, 0x00, 0x08, 0x03, 0x62, 0x61, 0x72, 0x03, 0x62, 0x61, 0x72, 0x00, 0x09, 0x0a,
+	0x6c, 0x69, 0x73, 0x74, 0x44, 0x6f, 0x75, 0x62, 0x6c, 0x65, 0x06, 0x04, 0x01, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xef, 0x7f, 0x09, 0x09, 0x6c, 0x69,
+	0x73, 0x74, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x00, 0x00, 0x09, 0x09, 0x6c, 0x69, 0x73, 0x74, 0x46,
+	0x6c, 0x6f, 0x61, 0x74, 0x05, 0x04, 0x01, 0x00, 0x00, 0x00, 0xff, 0xff, 0x7f, 0x7f, 0x09, 0x07,
+	0x6c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x03, 0x04, 0xff, 0xff, 0xff, 0xff, 0x0f, 0xfe, 0xff,
+	0xff, 0xff, 0x0f, 0x09, 0x0c, 0x6c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x74, 0x41, 0x72, 0x72, 0x61,
+	0x79, 0x0b, 0x04, 0x04, 0x00, 0x02, 0x04, 0x02, 0x00, 0x09, 0x08, 0x6c, 0x69, 0x73, 0x74, 0x4c,
+	0x69, 0x73, 0x74, 0x09, 0x04, 0x08, 0x02, 0x04, 0x7a, 0x65, 0x72, 0x6f, 0x08, 0x02, 0x03, 0x6f,
+	0x6e, 0x65, 0x09, 0x08, 0x6c, 0x69, 0x73, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x04, 0x04, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0x01, 0x09, 0x0d, 0x6c, 0x69, 0x73, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x41, 0x72, 0x72, 0x61,
+	0x79, 0x0c, 0x04, 0x04, 0x00, 0x02, 0x04, 0x02, 0x00, 0x09, 0x09, 0x6c, 0x69, 0x73, 0x74, 0x53,
+	0x68, 0x6f, 0x72, 0x74, 0x02, 0x04, 0x00, 0x80, 0xff, 0x7f, 0x09, 0x0a, 0x6c, 0x69, 0x73, 0x74,
+	0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x08, 0x04, 0x03, 0x66, 0x6f, 0x6f, 0x03, 0x62, 0x61, 0x72,
+	0x0c, 0x09, 0x6c, 0x6f, 0x6e, 0x67, 0x41, 0x72, 0x72, 0x61, 0x79, 0x04, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+	0x04, 0x07, 0x6c, 0x6f, 0x6e, 0x67, 0x4d, 0x61, 0x78, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0x01, 0x04, 0x07, 0x6c, 0x6f, 0x6e, 0x67, 0x4d, 0x69, 0x6e, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x4d, 0x61, 0x78,
+	0xff, 0x7f, 0x02, 0x08, 0x73, 0x68, 0x6f, 0x72, 0x74, 0x4d, 0x69, 0x6e, 0x00, 0x80, 0x08, 0x06,
+	0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x0d, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0x77, 0x6f,
+	0x72, 0x6c, 0x64, 0x21, 0x00,
+}
+
 var testJSON = []byte(`{
   "type": "Compound",
   "name": "root",
@@ -110,7 +110,7 @@ func (tag *NamedTag) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	payload, err := payloadUnmarshalJSON(_tag.Type, _tag.Payload)
+	payload, err := payloadUnmarshalJSON(_tag.Type, _tag.Payload, false)
 	if err != nil {
 		return err
 	}
@@ -121,6 +121,13 @@ func (tag *NamedTag) UnmarshalJSON(data []byte) error {
 }
 
 func payloadMarshalJSON(typ Type, payload interface{}) (json.RawMessage, error) {
+	if raw, ok := payload.(RawTag); ok {
+		var err error
+		if payload, err = raw.Decode(); err != nil {
+			return nil, err
+		}
+	}
+
 	var _payload interface{}
 	switch typ {
 	case TypeEnd:
@@ -144,7 +151,11 @@ func payloadMarshalJSON(typ Type, payload interface{}) (json.RawMessage, error)
 	case TypeList:
 		_payload = payload.(*List)
 	case TypeCompound:
-		_payload = payload.(Compound)
+		if c, ok := payload.(*OrderedCompound); ok {
+			_payload = c
+		} else {
+			_payload = payload.(Compound)
+		}
 	case TypeIntArray:
 		_payload = intArray(payload.([]int32))
 	case TypeLongArray:
@@ -158,7 +169,8 @@ func payloadMarshalJSON(typ Type, payload interface{}) (json.RawMessage, error)
 	return json.RawMessage(data), err
 }
 
-func payloadUnmarshalJSON(typ Type, data json.RawMessage) (interface{}, error) {
+// payloadUnmarshalJSON decodes compounds as OrderedCompound if ordered is set.
+func payloadUnmarshalJSON(typ Type, data json.RawMessage, ordered bool) (interface{}, error) {
 	payload, err := interface{}(nil), error(nil)
 	switch typ {
 	case TypeEnd:
@@ -200,12 +212,18 @@ func payloadUnmarshalJSON(typ Type, data json.RawMessage) (interface{}, error) {
 		payload = s
 	case TypeList:
 		l := new(List)
-		err = json.Unmarshal(data, l)
+		err = l.unmarshalJSON(data, ordered)
 		payload = l
 	case TypeCompound:
-		var m Compound
-		err = json.Unmarshal(data, &m)
-		payload = m
+		if ordered {
+			c := new(OrderedCompound)
+			err = json.Unmarshal(data, c)
+			payload = c
+		} else {
+			var m Compound
+			err = json.Unmarshal(data, &m)
+			payload = m
+		}
 	case TypeIntArray:
 		var a intArray
 		err = json.Unmarshal(data, &a)
@@ -351,6 +369,10 @@ func (tag *NamedTag) ToCompound() Compound {
 	return tag.Payload.(Compound)
 }
 
+func (tag *NamedTag) ToOrderedCompound() *OrderedCompound {
+	return tag.Payload.(*OrderedCompound)
+}
+
 func (tag *NamedTag) ToIntArray() []int32 {
 	return tag.Payload.([]int32)
 }
@@ -421,7 +443,11 @@ func (l *List) MarshalJSON() ([]byte, error) {
 	case TypeList:
 		_array = l.Array.([]*List)
 	case TypeCompound:
-		_array = l.Array.([]Compound)
+		if a, ok := l.Array.([]*OrderedCompound); ok {
+			_array = a
+		} else {
+			_array = l.Array.([]Compound)
+		}
 	case TypeIntArray:
 		as := make([]intArray, l.Length())
 		for i, a := range l.Array.([][]int32) {
@@ -447,6 +473,10 @@ func (l *List) MarshalJSON() ([]byte, error) {
 }
 
 func (l *List) UnmarshalJSON(data []byte) error {
+	return l.unmarshalJSON(data, false)
+}
+
+func (l *List) unmarshalJSON(data []byte, ordered bool) error {
 	_l := new(jsonList)
 	if err := json.Unmarshal(data, _l); err != nil {
 		return err
@@ -545,17 +575,34 @@ func (l *List) UnmarshalJSON(data []byte) error {
 		}
 		array = _array
 	case TypeList:
-		var _array []*List
-		if err := json.Unmarshal(_l.Array, &_array); err != nil {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(_l.Array, &raws); err != nil {
 			return err
 		}
+
+		_array := make([]*List, len(raws))
+		for i, raw := range raws {
+			_array[i] = new(List)
+			if err := _array[i].unmarshalJSON(raw, ordered); err != nil {
+				return err
+			}
+		}
+
 		array = _array
 	case TypeCompound:
-		var _array []Compound
-		if err := json.Unmarshal(_l.Array, &_array); err != nil {
-			return err
+		if ordered {
+			var _array []*OrderedCompound
+			if err := json.Unmarshal(_l.Array, &_array); err != nil {
+				return err
+			}
+			array = _array
+		} else {
+			var _array []Compound
+			if err := json.Unmarshal(_l.Array, &_array); err != nil {
+				return err
+			}
+			array = _array
 		}
-		array = _array
 	case TypeIntArray:
 		var as []intArray
 		if err := json.Unmarshal(_l.Array, &as); err != nil {
@@ -636,6 +683,10 @@ func (l *List) ToCompound() []Compound {
 	return l.Array.([]Compound)
 }
 
+func (l *List) ToOrderedCompound() []*OrderedCompound {
+	return l.Array.([]*OrderedCompound)
+}
+
 func (l *List) ToIntArray() [][]int32 {
 	return l.Array.([][]int32)
 }
@@ -665,19 +716,28 @@ func (tag *Tag) MarshalJSON() ([]byte, error) {
 }
 
 func (tag *Tag) UnmarshalJSON(data []byte) error {
+	_tag, err := unmarshalTagJSON(data, false)
+	if err != nil {
+		return err
+	}
+
+	*tag = *_tag
+
+	return nil
+}
+
+func unmarshalTagJSON(data []byte, ordered bool) (*Tag, error) {
 	_tag := new(jsonTag)
 	if err := json.Unmarshal(data, _tag); err != nil {
-		return err
+		return nil, err
 	}
 
-	payload, err := payloadUnmarshalJSON(_tag.Type, _tag.Payload)
+	payload, err := payloadUnmarshalJSON(_tag.Type, _tag.Payload, ordered)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	*tag = Tag{_tag.Type, payload}
-
-	return nil
+	return &Tag{_tag.Type, payload}, nil
 }
 
 func (tag *Tag) ToByte() int8 {
@@ -720,6 +780,10 @@ func (tag *Tag) ToCompound() Compound {
 	return tag.Payload.(Compound)
 }
 
+func (tag *Tag) ToOrderedCompound() *OrderedCompound {
+	return tag.Payload.(*OrderedCompound)
+}
+
 func (tag *Tag) ToIntArray() []int32 {
 	return tag.Payload.([]int32)
 }
@@ -0,0 +1,225 @@
+package nbt
+
+import (
+	"io"
+)
+
+// Writer writes NBT one tag at a time, so that large outputs can be
+// generated without building the whole tree in memory. Names are ignored for
+// elements of a list. Output is buffered until the root tag is complete or
+// Flush is called. After an error all further calls return the same error.
+type Writer struct {
+	enc   *Encoder
+	stack []writerFrame
+	err   error
+}
+
+type writerFrame struct {
+	typ       Type // List or Compound
+	elem      Type
+	remaining int
+	length    int
+
+	// path of the list or compound for EncodeError
+	path string
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterOptions(w, EncoderOptions{})
+}
+
+func NewWriterOptions(w io.Writer, opts EncoderOptions) *Writer {
+	return &Writer{enc: NewEncoderOptions(w, opts)}
+}
+
+// begin writes whatever precedes a payload of type typ in the current
+// position, which is the type and name in a compound or at the root.
+func (w *Writer) begin(name string, typ Type) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if typ < TypeByte || typ > TypeLongArray {
+		return w.fail(w.enc.errorf("unknown type (%v)", typ))
+	}
+
+	if len(w.stack) == 0 {
+		if err := w.enc.writeType(typ); err != nil {
+			return w.fail(err)
+		}
+		if !w.enc.namelessRoot {
+			return w.fail(w.enc.writeString(name))
+		}
+		return nil
+	}
+
+	f := &w.stack[len(w.stack)-1]
+	if f.typ == TypeList {
+		if typ != f.elem {
+			return w.fail(w.enc.errorf("list element type mismatch (%v, %v)", f.elem, typ))
+		}
+		if f.remaining == 0 {
+			return w.fail(w.enc.errorf("too many list elements"))
+		}
+		f.remaining--
+		return nil
+	}
+
+	if err := w.enc.writeType(typ); err != nil {
+		return w.fail(err)
+	}
+	return w.fail(w.enc.writeString(name))
+}
+
+// path returns the path of the tag that was just begun with the given name.
+func (w *Writer) path(name string) string {
+	if len(w.stack) == 0 {
+		if name == "" || w.enc.namelessRoot {
+			return ""
+		}
+		return pathName(name)
+	}
+
+	f := w.stack[len(w.stack)-1]
+	if f.typ == TypeList {
+		return f.path + indexSegment(f.length-f.remaining-1)
+	}
+	return f.path + "." + pathName(name)
+}
+
+func (w *Writer) fail(err error) error {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return err
+}
+
+func (w *Writer) BeginCompound(name string) error {
+	if err := w.begin(name, TypeCompound); err != nil {
+		return err
+	}
+	w.stack = append(w.stack, writerFrame{typ: TypeCompound, path: w.path(name)})
+	return nil
+}
+
+// BeginList starts a list of length elements of type elem, which must all be
+// written before calling End.
+func (w *Writer) BeginList(name string, elem Type, length int) error {
+	if err := w.begin(name, TypeList); err != nil {
+		return err
+	}
+
+	if elem > TypeLongArray || elem == TypeEnd && length > 0 {
+		return w.fail(w.enc.errorf("invalid list element type (%v)", elem))
+	}
+
+	if length < 0 {
+		return w.fail(w.enc.errorf("negative list length (%v)", length))
+	}
+
+	if err := w.enc.writeType(elem); err != nil {
+		return w.fail(err)
+	}
+
+	if err := w.enc.writeLength(length); err != nil {
+		return w.fail(err)
+	}
+
+	w.stack = append(w.stack, writerFrame{TypeList, elem, length, length, w.path(name)})
+	return nil
+}
+
+// End ends the innermost compound or list.
+func (w *Writer) End() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if len(w.stack) == 0 {
+		return w.fail(w.enc.errorf("no compound or list to end"))
+	}
+
+	f := w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+
+	if f.typ == TypeList && f.remaining > 0 {
+		return w.fail(w.enc.errorf("missing list elements (%d)", f.remaining))
+	}
+
+	if f.typ == TypeCompound {
+		if err := w.enc.writeType(TypeEnd); err != nil {
+			return w.fail(err)
+		}
+	}
+	return w.flushRoot()
+}
+
+// Flush writes any buffered output.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.fail(w.enc.flush())
+}
+
+// flushRoot flushes the output once the root tag is complete.
+func (w *Writer) flushRoot() error {
+	if len(w.stack) > 0 {
+		return nil
+	}
+	return w.fail(w.enc.flush())
+}
+
+// WriteTag writes a whole tag, such as a compound built in memory.
+func (w *Writer) WriteTag(name string, typ Type, payload interface{}) error {
+	if err := w.begin(name, typ); err != nil {
+		return err
+	}
+
+	if err := w.enc.writePayload(typ, payload); err != nil {
+		return w.fail(rootError(err, w.path(name), typ, payload))
+	}
+	return w.flushRoot()
+}
+
+// WriteInt8 writes a Byte tag. It is not called WriteByte to avoid confusion
+// with io.ByteWriter.
+func (w *Writer) WriteInt8(name string, v int8) error {
+	return w.WriteTag(name, TypeByte, v)
+}
+
+func (w *Writer) WriteShort(name string, v int16) error {
+	return w.WriteTag(name, TypeShort, v)
+}
+
+func (w *Writer) WriteInt(name string, v int32) error {
+	return w.WriteTag(name, TypeInt, v)
+}
+
+func (w *Writer) WriteLong(name string, v int64) error {
+	return w.WriteTag(name, TypeLong, v)
+}
+
+func (w *Writer) WriteFloat(name string, v float32) error {
+	return w.WriteTag(name, TypeFloat, v)
+}
+
+func (w *Writer) WriteDouble(name string, v float64) error {
+	return w.WriteTag(name, TypeDouble, v)
+}
+
+func (w *Writer) WriteByteArray(name string, v []byte) error {
+	return w.WriteTag(name, TypeByteArray, v)
+}
+
+func (w *Writer) WriteString(name string, v string) error {
+	return w.WriteTag(name, TypeString, v)
+}
+
+func (w *Writer) WriteIntArray(name string, v []int32) error {
+	return w.WriteTag(name, TypeIntArray, v)
+}
+
+func (w *Writer) WriteLongArray(name string, v []int64) error {
+	return w.WriteTag(name, TypeLongArray, v)
+}
@@ -0,0 +1,104 @@
+package nbt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriter(t *testing.T) {
+	expected := new(bytes.Buffer)
+	enc := NewEncoder(expected)
+	enc.SortCompounds(true)
+
+	err := enc.Encode(&NamedTag{TypeCompound, "root", Compound{
+		"entities": &Tag{TypeList, &List{TypeCompound, []Compound{
+			{"id": &Tag{TypeString, "minecraft:pig"}, "pos": &Tag{TypeList, &List{TypeDouble, []float64{1, 2, 3}}}},
+			{"id": &Tag{TypeString, "minecraft:cow"}, "pos": &Tag{TypeList, &List{TypeDouble, []float64{4, 5, 6}}}},
+		}}},
+		"onGround": &Tag{TypeByte, int8(1)},
+		"version":  &Tag{TypeInt, int32(3465)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(w.BeginCompound("root"))
+	check(w.BeginList("entities", TypeCompound, 2))
+	for i, id := range []string{"minecraft:pig", "minecraft:cow"} {
+		check(w.BeginCompound(""))
+		check(w.WriteString("id", id))
+		check(w.BeginList("pos", TypeDouble, 3))
+		for j := 1; j <= 3; j++ {
+			check(w.WriteDouble("", float64(i*3+j)))
+		}
+		check(w.End())
+		check(w.End())
+	}
+	check(w.End())
+	check(w.WriteInt8("onGround", 1))
+	check(w.WriteInt("version", 3465))
+	check(w.End())
+
+	if diff := cmp.Diff(expected.Bytes(), buf.Bytes()); diff != "" {
+		t.Fatalf("cmp.Diff(expected, got):\n%v", diff)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	tests := []func(w *Writer) error{
+		func(w *Writer) error {
+			return w.End()
+		},
+		func(w *Writer) error {
+			w.BeginList("", TypeInt, 1)
+			return w.WriteLong("", 1)
+		},
+		func(w *Writer) error {
+			w.BeginList("", TypeInt, 1)
+			return w.End()
+		},
+		func(w *Writer) error {
+			w.BeginList("", TypeInt, 1)
+			w.WriteInt("", 1)
+			return w.WriteInt("", 2)
+		},
+		func(w *Writer) error {
+			return w.BeginList("", TypeEnd, 1)
+		},
+		func(w *Writer) error {
+			w.BeginList("", TypeInt, -1)
+			return w.End()
+		},
+	}
+
+	for i, test := range tests {
+		if err := test(NewWriter(ioutil.Discard)); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestWriterEncodeError(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	w.BeginCompound("root")
+	w.BeginList("list", TypeCompound, 2)
+	w.WriteTag("", TypeCompound, Compound{})
+	err := w.WriteTag("", TypeCompound, Compound{"a": &Tag{TypeByte, 1}})
+
+	if e, ok := err.(*EncodeError); !ok || e.Path != "root.list[1].a" {
+		t.Fatalf("expected EncodeError at root.list[1].a, got %v", err)
+	}
+}